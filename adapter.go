@@ -0,0 +1,113 @@
+package stremigo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// errNoExchange - legacy provider needs the response writer and request, which are only available inside Router
+var errNoExchange = errors.New("stremigo: ProviderInterface called outside of Router")
+
+type exchangeKey struct{}
+
+// exchange - response writer and request of the currently served request
+type exchange struct {
+	w http.ResponseWriter
+	r *http.Request
+}
+
+func withExchange(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	return context.WithValue(ctx, exchangeKey{}, &exchange{w: w, r: r})
+}
+
+func exchangeFromContext(ctx context.Context) *exchange {
+	e, _ := ctx.Value(exchangeKey{}).(*exchange)
+	return e
+}
+
+// AdaptProvider - wraps ProviderInterface so it can be used as ContextProvider. A nil result of the wrapped
+// provider means it has already written the response (e.g. an error) and Router writes nothing more.
+// AddonCatalogProviderInterface is supported as well, otherwise AddonCatalog returns ErrNotFound.
+func AdaptProvider(p ProviderInterface) ContextProvider {
+	return &legacyProvider{p: p}
+}
+
+// legacyProvider - ContextProvider calling ProviderInterface with the writer and request stored in context
+type legacyProvider struct {
+	p ProviderInterface
+}
+
+// call - calls fn with the current writer and request and converts a nil result to errResponseWritten
+func call[T any](ctx context.Context, fn func(w http.ResponseWriter, r *http.Request) *T) (*T, error) {
+	e := exchangeFromContext(ctx)
+	if e == nil {
+		return nil, errNoExchange
+	}
+
+	if v := fn(e.w, e.r); v != nil {
+		return v, nil
+	}
+	return nil, errResponseWritten
+}
+
+func (l *legacyProvider) Manifest(ctx context.Context, token string) (*AddonManifest, error) {
+	return call(
+		ctx, func(w http.ResponseWriter, r *http.Request) *AddonManifest {
+			return l.p.GetManifest(w, r, token)
+		},
+	)
+}
+
+func (l *legacyProvider) Catalog(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+	return call(
+		ctx, func(w http.ResponseWriter, r *http.Request) *MetaPreviewList {
+			return l.p.GetCatalog(w, r, token, args)
+		},
+	)
+}
+
+func (l *legacyProvider) Meta(ctx context.Context, token string, args *MetaArgs) (*Meta, error) {
+	return call(
+		ctx, func(w http.ResponseWriter, r *http.Request) *Meta {
+			return l.p.GetMeta(w, r, token, args)
+		},
+	)
+}
+
+func (l *legacyProvider) Stream(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+	return call(
+		ctx, func(w http.ResponseWriter, r *http.Request) *StreamList {
+			return l.p.GetStream(w, r, token, args)
+		},
+	)
+}
+
+func (l *legacyProvider) Subtitles(ctx context.Context, token string, args *SubtitlesArgs) (*SubtitlesList, error) {
+	return call(
+		ctx, func(w http.ResponseWriter, r *http.Request) *SubtitlesList {
+			return l.p.GetSubtitles(w, r, token, args)
+		},
+	)
+}
+
+func (l *legacyProvider) AddonCatalog(ctx context.Context, token string, args *AddonCatalogArgs) (*AddonCatalogList, error) {
+	ap, ok := l.p.(AddonCatalogProviderInterface)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return call(
+		ctx, func(w http.ResponseWriter, r *http.Request) *AddonCatalogList {
+			return ap.GetAddonCatalog(w, r, token, args)
+		},
+	)
+}
+
+func (l *legacyProvider) RenderConfigurePage(w http.ResponseWriter, r *http.Request, token string) {
+	l.p.RenderConfigurePage(w, r, token)
+}
+
+func (l *legacyProvider) IsSecured() bool {
+	return l.p.IsSecured()
+}
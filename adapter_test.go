@@ -0,0 +1,59 @@
+package stremigo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// writingProvider - mockProvider writing its own error response for every stream request
+type writingProvider struct {
+	mockProvider
+}
+
+func (p *writingProvider) GetStream(w http.ResponseWriter, r *http.Request, token string, args *StreamArgs) *StreamList {
+	http.Error(w, "Upstream down", http.StatusBadGateway)
+	return nil
+}
+
+func TestAdaptProviderOutsideRouter(t *testing.T) {
+	_, err := AdaptProvider(&mockProvider{}).Stream(context.Background(), "", &StreamArgs{})
+	if !errors.Is(err, errNoExchange) {
+		t.Errorf("Stream() error = %v, want %v", err, errNoExchange)
+	}
+}
+
+func TestAdaptProviderWrittenResponse(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	Router(rr, httptest.NewRequest(http.MethodGet, "/stream/movie/tt0111161.json", nil), &writingProvider{})
+
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusBadGateway)
+	}
+	if got, want := rr.Body.String(), "Upstream down\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestAdaptProviderSecured(t *testing.T) {
+	tests := []struct {
+		name    string
+		secured bool
+	}{
+		{name: "secured", secured: true},
+		{name: "unsecured", secured: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := AdaptProvider(&mockProvider{secured: tt.secured}).IsSecured(); got != tt.secured {
+					t.Errorf("IsSecured() = %v, want %v", got, tt.secured)
+				}
+			},
+		)
+	}
+}
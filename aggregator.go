@@ -0,0 +1,462 @@
+package stremigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUpstreamTimeout - deadline of a single upstream call of Aggregator
+const DefaultUpstreamTimeout = 5 * time.Second
+
+// DefaultUpstreamManifestTTL - how long Aggregator keeps manifests of the upstreams
+const DefaultUpstreamManifestTTL = 10 * time.Minute
+
+// DefaultUpstreamFailureTTL - how long Aggregator keeps a failed fetch of an upstream manifest before retrying it
+const DefaultUpstreamFailureTTL = 30 * time.Second
+
+// Upstream - addon aggregated by Aggregator, implemented by AddonClient
+type Upstream interface {
+	Manifest(ctx context.Context) (*AddonManifest, error)
+	Catalog(ctx context.Context, args *CatalogArgs) (*MetaPreviewList, error)
+	Meta(ctx context.Context, args *MetaArgs) (*Meta, error)
+	Stream(ctx context.Context, args *StreamArgs) (*StreamList, error)
+	Subtitles(ctx context.Context, args *SubtitlesArgs) (*SubtitlesList, error)
+}
+
+// AggregatorOption - configures Aggregator created by NewAggregator
+type AggregatorOption func(a *Aggregator)
+
+// Aggregator - ContextProvider fanning requests out to the upstreams in parallel. Streams and subtitles
+// of all upstreams are merged without duplicates, catalogs are interleaved and meta is taken from the first
+// upstream in order providing it. Requests are sent only to upstreams declaring the resource, type and id,
+// see AddonManifest.AcceptsID. Failed upstreams are skipped, the request fails only when all of them fail.
+type Aggregator struct {
+	manifest    AddonManifest
+	upstreams   []Upstream
+	timeout     time.Duration
+	timeouts    map[int]time.Duration
+	manifestTTL time.Duration
+	failureTTL  time.Duration
+	logger      *slog.Logger
+	now         func() time.Time
+
+	mu        sync.Mutex
+	manifests map[int]*upstreamManifest
+}
+
+// upstreamManifest - cached manifest of an upstream, or the error of its failed fetch
+type upstreamManifest struct {
+	manifest *AddonManifest
+	err      error
+	expires  time.Time
+}
+
+// NewAggregator - creates Aggregator of the upstreams, ordered by priority; manifest holds the descriptive
+// fields (ID, Version, Name, ...), resources, types and catalogs are merged from the upstreams
+func NewAggregator(manifest AddonManifest, upstreams []Upstream, opts ...AggregatorOption) *Aggregator {
+	a := &Aggregator{
+		manifest:    manifest,
+		upstreams:   slices.Clone(upstreams),
+		timeout:     DefaultUpstreamTimeout,
+		manifestTTL: DefaultUpstreamManifestTTL,
+		failureTTL:  DefaultUpstreamFailureTTL,
+		now:         time.Now,
+		manifests:   map[int]*upstreamManifest{},
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// WithUpstreamTimeout - deadline of every upstream call including the fetch of its manifest,
+// DefaultUpstreamTimeout by default; slower upstreams are skipped
+func WithUpstreamTimeout(timeout time.Duration) AggregatorOption {
+	return func(a *Aggregator) {
+		a.timeout = timeout
+	}
+}
+
+// WithUpstreamTimeoutOf - replaces WithUpstreamTimeout for the upstream at the index, e.g. for a slow one
+// worth waiting for
+func WithUpstreamTimeoutOf(index int, timeout time.Duration) AggregatorOption {
+	return func(a *Aggregator) {
+		if a.timeouts == nil {
+			a.timeouts = map[int]time.Duration{}
+		}
+		a.timeouts[index] = timeout
+	}
+}
+
+// timeoutOf - returns deadline of the upstream at the index
+func (a *Aggregator) timeoutOf(i int) time.Duration {
+	if timeout, ok := a.timeouts[i]; ok {
+		return timeout
+	}
+	return a.timeout
+}
+
+// WithUpstreamManifestTTL - how long manifests of the upstreams are kept, DefaultUpstreamManifestTTL by default
+func WithUpstreamManifestTTL(ttl time.Duration) AggregatorOption {
+	return func(a *Aggregator) {
+		a.manifestTTL = ttl
+	}
+}
+
+// WithUpstreamFailureTTL - how long a failed fetch of an upstream manifest is kept, so dead upstreams are not
+// retried by every request; DefaultUpstreamFailureTTL by default
+func WithUpstreamFailureTTL(ttl time.Duration) AggregatorOption {
+	return func(a *Aggregator) {
+		a.failureTTL = ttl
+	}
+}
+
+// WithAggregatorLogger - logs failures of the upstreams
+func WithAggregatorLogger(logger *slog.Logger) AggregatorOption {
+	return func(a *Aggregator) {
+		a.logger = logger
+	}
+}
+
+// upstreamManifest - returns manifest of the upstream, cached for manifestTTL; failures are cached for failureTTL
+// unless the request was canceled
+func (a *Aggregator) upstreamManifest(ctx context.Context, i int) (*AddonManifest, error) {
+	a.mu.Lock()
+	cached, ok := a.manifests[i]
+	a.mu.Unlock()
+	if ok && a.now().Before(cached.expires) {
+		return cached.manifest, cached.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, a.timeoutOf(i))
+	defer cancel()
+
+	manifest, err := a.upstreams[i].Manifest(ctx)
+	if err == nil && manifest == nil {
+		err = ErrNotFound
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil, err
+	}
+
+	ttl := a.manifestTTL
+	if err != nil {
+		manifest, ttl = nil, a.failureTTL
+	}
+
+	a.mu.Lock()
+	a.manifests[i] = &upstreamManifest{manifest: manifest, err: err, expires: a.now().Add(ttl)}
+	a.mu.Unlock()
+	return manifest, err
+}
+
+// errNotDeclared - the upstream doesn't declare the requested resource
+var errNotDeclared = fmt.Errorf("%w: not declared by upstream", ErrNotFound)
+
+// fanOut - calls the upstreams declaring the resource in parallel, each within its own deadline covering
+// the fetch of its manifest as well; returns
+// results in the order of the upstreams, failing only when no upstream succeeded
+func fanOut[T any](ctx context.Context, a *Aggregator, resource, typ, id string, call func(ctx context.Context, u Upstream) (*T, error)) ([]*T, error) {
+	results := make([]*T, len(a.upstreams))
+	errs := make([]error, len(a.upstreams))
+
+	var wg sync.WaitGroup
+	for i, u := range a.upstreams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			uctx, cancel := context.WithTimeout(ctx, a.timeoutOf(i))
+			defer cancel()
+
+			manifest, err := a.upstreamManifest(uctx, i)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if !manifest.AcceptsID(resource, typ, id) {
+				errs[i] = errNotDeclared
+				return
+			}
+
+			if results[i], errs[i] = call(uctx, u); errs[i] == nil && results[i] == nil {
+				errs[i] = ErrNotFound
+			}
+		}()
+	}
+	wg.Wait()
+
+	values := make([]*T, 0, len(results))
+	var failures []error
+	for i, v := range results {
+		if v != nil {
+			values = append(values, v)
+			continue
+		}
+		if errors.Is(errs[i], ErrNotFound) {
+			continue
+		}
+		failures = append(failures, fmt.Errorf("upstream %d: %w", i, errs[i]))
+		if a.logger != nil {
+			a.logger.LogAttrs(ctx, slog.LevelWarn, "stremigo upstream failed", slog.Int("upstream", i), slog.String("resource", resource), slog.Any("error", errs[i]))
+		}
+	}
+
+	switch {
+	case len(values) > 0:
+		return values, nil
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case len(failures) > 0:
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, errors.Join(failures...))
+	default:
+		return nil, ErrNotFound
+	}
+}
+
+// Manifest - returns the manifest with resources, types and catalogs merged from the upstreams
+func (a *Aggregator) Manifest(ctx context.Context, token string) (*AddonManifest, error) {
+	manifest := a.manifest
+	manifest.Types = slices.Clone(manifest.Types)
+	manifest.Resources = nil
+	manifest.Catalogs = []*Catalog{}
+	manifest.Prefixes = nil
+
+	// the manifests are fetched in parallel, so dead upstreams delay the manifest by a single deadline
+	manifests := make([]*AddonManifest, len(a.upstreams))
+	errs := make([]error, len(a.upstreams))
+	var wg sync.WaitGroup
+	for i := range a.upstreams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			manifests[i], errs[i] = a.upstreamManifest(ctx, i)
+		}()
+	}
+	wg.Wait()
+
+	loaded := 0
+	resources := map[string]*Resource{}
+	var names []string
+
+	for i, m := range manifests {
+		if errs[i] != nil {
+			if a.logger != nil {
+				a.logger.LogAttrs(ctx, slog.LevelWarn, "stremigo upstream failed", slog.Int("upstream", i), slog.String("resource", PathManifest), slog.Any("error", errs[i]))
+			}
+			continue
+		}
+		loaded++
+
+		manifest.Types = appendUnique(manifest.Types, m.Types...)
+		for _, c := range m.Catalogs {
+			if c != nil && !slices.ContainsFunc(manifest.Catalogs, func(o *Catalog) bool { return o.Type == c.Type && o.ID == c.ID }) {
+				manifest.Catalogs = append(manifest.Catalogs, c)
+			}
+		}
+
+		for _, r := range m.Resources {
+			if r == nil || r.Name == ResourceAddonCatalog {
+				continue
+			}
+			types, prefixes := r.Type, r.Prefixes
+			if len(types) == 0 {
+				types = m.Types
+			}
+			if len(prefixes) == 0 {
+				prefixes = m.Prefixes
+			}
+
+			merged, ok := resources[r.Name]
+			if !ok {
+				merged = &Resource{Name: r.Name, Prefixes: slices.Clone(prefixes)}
+				resources[r.Name] = merged
+				names = append(names, r.Name)
+			} else if len(merged.Prefixes) > 0 && len(prefixes) > 0 {
+				merged.Prefixes = appendUnique(merged.Prefixes, prefixes...)
+			} else {
+				// one of the upstreams accepts every id
+				merged.Prefixes = []string{}
+			}
+			merged.Type = appendUnique(merged.Type, types...)
+		}
+	}
+
+	if loaded == 0 && len(a.upstreams) > 0 {
+		return nil, fmt.Errorf("%w: no upstream manifest", ErrUpstreamUnavailable)
+	}
+
+	for _, name := range names {
+		r := resources[name]
+		if len(r.Prefixes) == 0 {
+			r.Prefixes = nil
+		}
+		if name == ResourceCatalog {
+			r = &Resource{Name: ResourceCatalog}
+		}
+		manifest.Resources = append(manifest.Resources, r)
+	}
+
+	return &manifest, nil
+}
+
+// Catalog - interleaves catalogs of the upstreams, dropping items already listed by a preceding upstream
+func (a *Aggregator) Catalog(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+	lists, err := fanOut(ctx, a, ResourceCatalog, args.Type, args.ID, func(ctx context.Context, u Upstream) (*MetaPreviewList, error) {
+		return u.Catalog(ctx, args)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &MetaPreviewList{Metas: []*MetaPreview{}}
+	seen := map[string]bool{}
+	for i := 0; ; i++ {
+		more := false
+		for _, l := range lists {
+			if i >= len(l.Metas) {
+				continue
+			}
+			more = true
+			if m := l.Metas[i]; m != nil && !seen[m.ID] {
+				seen[m.ID] = true
+				merged.Metas = append(merged.Metas, m)
+			}
+		}
+		if !more {
+			break
+		}
+	}
+
+	policy := shortestCachePolicy(lists)
+	merged.CacheMaxAge, merged.StaleRevalidate, merged.StaleError = policy.MaxAge, policy.StaleRevalidate, policy.StaleError
+	return merged, nil
+}
+
+// Meta - returns meta of the first upstream in order providing it
+func (a *Aggregator) Meta(ctx context.Context, token string, args *MetaArgs) (*Meta, error) {
+	metas, err := fanOut(ctx, a, ResourceMeta, args.Type, args.ID, func(ctx context.Context, u Upstream) (*Meta, error) {
+		return u.Meta(ctx, args)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metas[0], nil
+}
+
+// Stream - merges streams of the upstreams in their order, see StreamKey for duplicates detection
+func (a *Aggregator) Stream(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+	lists, err := fanOut(ctx, a, ResourceStream, args.Type, args.ID, func(ctx context.Context, u Upstream) (*StreamList, error) {
+		return u.Stream(ctx, args)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &StreamList{Streams: []*Stream{}}
+	seen := map[string]bool{}
+	for _, l := range lists {
+		for _, s := range l.Streams {
+			if s == nil {
+				continue
+			}
+			if key := StreamKey(s); key != "" {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			merged.Streams = append(merged.Streams, s)
+		}
+	}
+
+	policy := shortestCachePolicy(lists)
+	merged.CacheMaxAge, merged.StaleRevalidate, merged.StaleError = policy.MaxAge, policy.StaleRevalidate, policy.StaleError
+	return merged, nil
+}
+
+// Subtitles - merges subtitles of the upstreams in their order, duplicates are detected by URL
+func (a *Aggregator) Subtitles(ctx context.Context, token string, args *SubtitlesArgs) (*SubtitlesList, error) {
+	lists, err := fanOut(ctx, a, ResourceSubtitles, args.Type, args.ID, func(ctx context.Context, u Upstream) (*SubtitlesList, error) {
+		return u.Subtitles(ctx, args)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &SubtitlesList{Subtitles: []*Subtitles{}}
+	seen := map[string]bool{}
+	for _, l := range lists {
+		for _, s := range l.Subtitles {
+			if s == nil || seen[s.URL] {
+				continue
+			}
+			seen[s.URL] = true
+			merged.Subtitles = append(merged.Subtitles, s)
+		}
+	}
+
+	policy := shortestCachePolicy(lists)
+	merged.CacheMaxAge, merged.StaleRevalidate, merged.StaleError = policy.MaxAge, policy.StaleRevalidate, policy.StaleError
+	return merged, nil
+}
+
+func (a *Aggregator) RenderConfigurePage(w http.ResponseWriter, r *http.Request, token string) {
+	WriteErrorResponse(w, NewErrorResponse(ErrNotFound, DefaultErrorMessages))
+}
+
+func (a *Aggregator) IsSecured() bool {
+	return false
+}
+
+// StreamKey - returns identity of the stream used to detect duplicates: info hash with file index for
+// torrents, otherwise URL, YouTube id or external URL; empty for streams without any of them
+func StreamKey(s *Stream) string {
+	switch {
+	case s.InfoHash != "":
+		return "btih:" + strings.ToLower(s.InfoHash) + ":" + strconv.Itoa(s.FileIdx)
+	case s.URL != "":
+		return "url:" + s.URL
+	case s.YtId != "":
+		return "yt:" + s.YtId
+	case s.ExternalUrl != "":
+		return "external:" + s.ExternalUrl
+	default:
+		return ""
+	}
+}
+
+// shortestCachePolicy - returns the shortest cache policy of the lists, so the merged response is not cached
+// longer than any upstream allows; upstreams setting no value leave it to the others
+func shortestCachePolicy[L cacheable](lists []L) CachePolicy {
+	var policy CachePolicy
+	for _, l := range lists {
+		p := l.CachePolicy()
+		policy.MaxAge = minSet(policy.MaxAge, p.MaxAge)
+		policy.StaleRevalidate = minSet(policy.StaleRevalidate, p.StaleRevalidate)
+		policy.StaleError = minSet(policy.StaleError, p.StaleError)
+	}
+	return policy
+}
+
+// minSet - returns the smaller of the values, zero meaning not set
+func minSet(a, b int) int {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
@@ -0,0 +1,354 @@
+package stremigo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var _ Upstream = (*AddonClient)(nil)
+
+// fakeUpstream - in-memory Upstream
+type fakeUpstream struct {
+	manifest *AddonManifest
+	metas    []*MetaPreview
+	meta     *Meta
+	streams  *StreamList
+	err      error
+	delay    time.Duration
+	calls    atomic.Int32
+
+	manifestDelay time.Duration
+	manifests     atomic.Int32
+}
+
+func (u *fakeUpstream) wait(ctx context.Context) error {
+	u.calls.Add(1)
+	if u.delay == 0 {
+		return u.err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(u.delay):
+		return u.err
+	}
+}
+
+func (u *fakeUpstream) Manifest(ctx context.Context) (*AddonManifest, error) {
+	u.manifests.Add(1)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(u.manifestDelay):
+	}
+	if u.manifest == nil {
+		return nil, ErrUpstreamUnavailable
+	}
+	return u.manifest, nil
+}
+
+func (u *fakeUpstream) Catalog(ctx context.Context, args *CatalogArgs) (*MetaPreviewList, error) {
+	if err := u.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &MetaPreviewList{Metas: u.metas}, nil
+}
+
+func (u *fakeUpstream) Meta(ctx context.Context, args *MetaArgs) (*Meta, error) {
+	if err := u.wait(ctx); err != nil {
+		return nil, err
+	}
+	return u.meta, nil
+}
+
+func (u *fakeUpstream) Stream(ctx context.Context, args *StreamArgs) (*StreamList, error) {
+	if err := u.wait(ctx); err != nil {
+		return nil, err
+	}
+	return u.streams, nil
+}
+
+func (u *fakeUpstream) Subtitles(ctx context.Context, args *SubtitlesArgs) (*SubtitlesList, error) {
+	if err := u.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &SubtitlesList{Subtitles: []*Subtitles{}}, nil
+}
+
+func upstreamManifestOf(prefixes []string, resources ...*Resource) *AddonManifest {
+	return &AddonManifest{
+		Types:     []string{TypeMovie, TypeSeries},
+		Prefixes:  prefixes,
+		Resources: resources,
+		Catalogs:  []*Catalog{{ID: "top", Type: TypeMovie, Name: "Top"}},
+	}
+}
+
+func TestAggregatorStream(t *testing.T) {
+	first := &fakeUpstream{
+		manifest: upstreamManifestOf([]string{PrefixImdb}, &Resource{Name: ResourceStream}),
+		streams: &StreamList{
+			Streams: []*Stream{
+				{InfoHash: "ABC", FileIdx: 1, Name: "first torrent"},
+				{URL: "https://example.com/a.mp4", Name: "first url"},
+			},
+			CacheMaxAge: 3600,
+		},
+	}
+	second := &fakeUpstream{
+		manifest: upstreamManifestOf(nil, &Resource{Name: ResourceStream}),
+		streams: &StreamList{
+			Streams: []*Stream{
+				{InfoHash: "abc", FileIdx: 1, Name: "duplicate torrent"},
+				{InfoHash: "abc", FileIdx: 2, Name: "other file"},
+				{URL: "https://example.com/a.mp4", Name: "duplicate url"},
+				{YtId: "dQw4w9WgXcQ", Name: "youtube"},
+			},
+			CacheMaxAge: 600,
+		},
+	}
+	failing := &fakeUpstream{manifest: upstreamManifestOf(nil, &Resource{Name: ResourceStream}), err: ErrUpstreamUnavailable}
+	slow := &fakeUpstream{manifest: upstreamManifestOf(nil, &Resource{Name: ResourceStream}), delay: time.Second, streams: &StreamList{}}
+	kitsu := &fakeUpstream{manifest: upstreamManifestOf([]string{PrefixKitsu}, &Resource{Name: ResourceStream})}
+
+	a := NewAggregator(testManifest, []Upstream{first, second, failing, slow, kitsu}, WithUpstreamTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	got, err := a.Stream(context.Background(), "", &StreamArgs{Type: TypeMovie, ID: "tt0111161"})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Stream() took %v, want the slow upstream cut by its deadline", elapsed)
+	}
+
+	var names []string
+	for _, s := range got.Streams {
+		names = append(names, s.Name)
+	}
+	if want := []string{"first torrent", "first url", "other file", "youtube"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("streams = %v, want %v", names, want)
+	}
+	if got.CacheMaxAge != 600 {
+		t.Errorf("CacheMaxAge = %d, want the shortest one of the upstreams", got.CacheMaxAge)
+	}
+	if kitsu.calls.Load() != 0 {
+		t.Errorf("upstream of other prefix called %d times, want 0", kitsu.calls.Load())
+	}
+}
+
+func TestAggregatorCatalog(t *testing.T) {
+	first := &fakeUpstream{
+		manifest: upstreamManifestOf(nil, &Resource{Name: ResourceCatalog}),
+		metas:    []*MetaPreview{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+	}
+	second := &fakeUpstream{
+		manifest: upstreamManifestOf(nil, &Resource{Name: ResourceCatalog}),
+		metas:    []*MetaPreview{{ID: "x"}, {ID: "a"}},
+	}
+
+	got, err := NewAggregator(testManifest, []Upstream{first, second}).
+		Catalog(context.Background(), "", &CatalogArgs{Type: TypeMovie, ID: "top"})
+	if err != nil {
+		t.Fatalf("Catalog() error = %v", err)
+	}
+
+	var ids []string
+	for _, m := range got.Metas {
+		ids = append(ids, m.ID)
+	}
+	if want := []string{"a", "x", "b", "c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("metas = %v, want %v", ids, want)
+	}
+}
+
+func TestAggregatorMeta(t *testing.T) {
+	missing := &fakeUpstream{manifest: upstreamManifestOf(nil, &Resource{Name: ResourceMeta}), err: ErrNotFound}
+	found := &fakeUpstream{manifest: upstreamManifestOf(nil, &Resource{Name: ResourceMeta}), meta: &Meta{ID: "tt0111161", Name: "second"}}
+	later := &fakeUpstream{manifest: upstreamManifestOf(nil, &Resource{Name: ResourceMeta}), meta: &Meta{ID: "tt0111161", Name: "third"}}
+
+	got, err := NewAggregator(testManifest, []Upstream{missing, found, later}).
+		Meta(context.Background(), "", &MetaArgs{Type: TypeMovie, ID: "tt0111161"})
+	if err != nil {
+		t.Fatalf("Meta() error = %v", err)
+	}
+	if got.Name != "second" {
+		t.Errorf("Meta() = %q, want meta of the first upstream providing it", got.Name)
+	}
+}
+
+func TestAggregatorDeadline(t *testing.T) {
+	newSlow := func() *fakeUpstream {
+		return &fakeUpstream{
+			manifest:      upstreamManifestOf(nil, &Resource{Name: ResourceStream}),
+			manifestDelay: 40 * time.Millisecond,
+			delay:         40 * time.Millisecond,
+			streams:       &StreamList{Streams: []*Stream{{URL: "https://example.com/slow.mp4"}}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		opts    []AggregatorOption
+		wantErr error
+	}{
+		{
+			name:    "manifest and call share the deadline",
+			opts:    []AggregatorOption{WithUpstreamTimeout(60 * time.Millisecond)},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "deadline of the upstream",
+			opts: []AggregatorOption{WithUpstreamTimeout(60 * time.Millisecond), WithUpstreamTimeoutOf(0, time.Second)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := NewAggregator(testManifest, []Upstream{newSlow()}, tt.opts...).
+					Stream(context.Background(), "", &StreamArgs{Type: TypeMovie, ID: "tt0111161"})
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Stream() error = %v, want %v", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+func TestShortestCachePolicy(t *testing.T) {
+	lists := []*StreamList{
+		{CacheMaxAge: 3600, StaleError: 600},
+		{},
+		{CacheMaxAge: 600, StaleRevalidate: 60},
+	}
+
+	want := CachePolicy{MaxAge: 600, StaleRevalidate: 60, StaleError: 600}
+	if got := shortestCachePolicy(lists); got != want {
+		t.Errorf("shortestCachePolicy() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAggregatorErrors(t *testing.T) {
+	notFound := &fakeUpstream{manifest: upstreamManifestOf(nil, &Resource{Name: ResourceStream}), err: ErrNotFound}
+	failing := &fakeUpstream{manifest: upstreamManifestOf(nil, &Resource{Name: ResourceStream}), err: errors.New("boom")}
+	undeclared := &fakeUpstream{manifest: upstreamManifestOf(nil, &Resource{Name: ResourceMeta})}
+
+	tests := []struct {
+		name      string
+		upstreams []Upstream
+		wantErr   error
+	}{
+		{name: "all not found", upstreams: []Upstream{notFound, undeclared}, wantErr: ErrNotFound},
+		{name: "all failed", upstreams: []Upstream{notFound, failing}, wantErr: ErrUpstreamUnavailable},
+		{name: "no manifest", upstreams: []Upstream{&fakeUpstream{}}, wantErr: ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := NewAggregator(testManifest, tt.upstreams).
+					Stream(context.Background(), "", &StreamArgs{Type: TypeMovie, ID: "tt0111161"})
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Stream() error = %v, want %v", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+func TestAggregatorManifest(t *testing.T) {
+	imdb := &fakeUpstream{
+		manifest: upstreamManifestOf(
+			[]string{PrefixImdb},
+			&Resource{Name: ResourceCatalog},
+			&Resource{Name: ResourceStream, Type: []string{TypeMovie}},
+		),
+	}
+	kitsu := &fakeUpstream{
+		manifest: &AddonManifest{
+			Types:     []string{TypeSeries},
+			Resources: []*Resource{{Name: ResourceStream, Prefixes: []string{PrefixKitsu}}, {Name: ResourceMeta}},
+			Catalogs:  []*Catalog{{ID: "top", Type: TypeMovie, Name: "Duplicate"}, {ID: "anime", Type: TypeSeries, Name: "Anime"}},
+		},
+	}
+
+	got, err := NewAggregator(testManifest, []Upstream{imdb, kitsu, &fakeUpstream{}}).Manifest(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+
+	if got.ID != testManifest.ID {
+		t.Errorf("ID = %q, want the aggregator manifest id", got.ID)
+	}
+	wantResources := []*Resource{
+		{Name: ResourceCatalog},
+		{Name: ResourceStream, Type: []string{TypeMovie, TypeSeries}, Prefixes: []string{PrefixImdb, PrefixKitsu}},
+		{Name: ResourceMeta, Type: []string{TypeSeries}},
+	}
+	if !reflect.DeepEqual(got.Resources, wantResources) {
+		t.Errorf("Resources = %+v, want %+v", got.Resources, wantResources)
+	}
+	if want := []string{TypeMovie, TypeSeries}; !reflect.DeepEqual(got.Types, want) {
+		t.Errorf("Types = %v, want %v", got.Types, want)
+	}
+	if len(got.Catalogs) != 2 || got.Catalogs[0].Name != "Top" || got.Catalogs[1].ID != "anime" {
+		t.Errorf("Catalogs = %+v, want top of the first upstream and anime", got.Catalogs)
+	}
+	if err = got.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestAggregatorManifestDeadUpstreams(t *testing.T) {
+	live := &fakeUpstream{manifest: upstreamManifestOf(nil, &Resource{Name: ResourceStream})}
+	upstreams := []Upstream{live}
+	var dead []*fakeUpstream
+	for range 4 {
+		u := &fakeUpstream{manifest: live.manifest, manifestDelay: time.Second}
+		dead = append(dead, u)
+		upstreams = append(upstreams, u)
+	}
+
+	clock := &testClock{now: time.Now()}
+	a := NewAggregator(testManifest, upstreams, WithUpstreamTimeout(200*time.Millisecond), WithUpstreamFailureTTL(time.Minute))
+	a.now = clock.Now
+
+	start := time.Now()
+	if _, err := a.Manifest(context.Background(), ""); err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 600*time.Millisecond {
+		t.Errorf("Manifest() took %v, want the dead upstreams fetched in parallel", elapsed)
+	}
+
+	a.Manifest(context.Background(), "")
+	if got := dead[0].manifests.Load(); got != 1 {
+		t.Errorf("dead upstream fetched %d times, want 1 as its failure is kept", got)
+	}
+
+	clock.Add(time.Minute)
+	a.Manifest(context.Background(), "")
+	if got := dead[0].manifests.Load(); got != 2 {
+		t.Errorf("dead upstream fetched %d times, want 2 after the failure expired", got)
+	}
+}
+
+func TestAggregatorConfigurePage(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	NewServer(NewAggregator(testManifest, nil)).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+PathConfigure, nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want the JSON error envelope", ct)
+	}
+}
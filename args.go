@@ -0,0 +1,146 @@
+package stremigo
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPath - returned when a resource path doesn't follow /{resource}/{type}/{id}[/{extra}].json
+var ErrInvalidPath = fmt.Errorf("%w: invalid resource path", ErrBadRequest)
+
+const jsonSuffix = ".json"
+
+// AddonCatalogArgs - arguments of /addon_catalog/{type}/{id}[/{extra}].json request
+// Type - content type of the addon catalog. [ TypeMovie, TypeSeries, TypeChannel, TypeTv ]
+// ID - Catalog.ID of the requested addon catalog, declared in AddonManifest.AddonCatalogs
+// Extra - extra properties of the request, if any
+type AddonCatalogArgs struct {
+	Type  string
+	ID    string
+	Extra ExtraArgs
+}
+
+// CatalogArgs - arguments of /catalog/{type}/{id}[/{extra}].json request
+// Type - content type of the catalog. [ TypeMovie, TypeSeries, TypeChannel, TypeTv ]
+// ID - Catalog.ID of the requested catalog
+// Extra - extra properties of the request, e.g. search, genre or skip (see CatalogExtra* constants and ExtraArgs.Validate)
+type CatalogArgs struct {
+	Type  string
+	ID    string
+	Extra ExtraArgs
+}
+
+// MetaArgs - arguments of /meta/{type}/{id}[/{extra}].json request
+// Type - content type of the requested item. [ TypeMovie, TypeSeries, TypeChannel, TypeTv ]
+// ID - Meta.ID of the requested item
+// Extra - extra properties of the request, if any
+type MetaArgs struct {
+	Type  string
+	ID    string
+	Extra ExtraArgs
+}
+
+// StreamArgs - arguments of /stream/{type}/{id}[/{extra}].json request
+// Type - content type of the requested video. [ TypeMovie, TypeSeries, TypeChannel, TypeTv ]
+// ID - Video.ID of the requested video, e.g. "tt0111161" or "tt0903747:1:1" for series
+// Extra - extra properties of the request, if any
+type StreamArgs struct {
+	Type  string
+	ID    string
+	Extra ExtraArgs
+}
+
+// SubtitlesArgs - arguments of /subtitles/{type}/{id}[/{extra}].json request
+// Type - content type of the requested video. [ TypeMovie, TypeSeries, TypeChannel, TypeTv ]
+// ID - Video.ID of the requested video
+// Extra - extra properties of the request, if any
+// VideoHash - OpenSubtitles hash of the video, parsed from SubtitlesExtraVideoHash
+// VideoSize - size of the video file in bytes, parsed from SubtitlesExtraVideoSize
+// Filename - filename of the video file, parsed from SubtitlesExtraFilename
+type SubtitlesArgs struct {
+	Type      string
+	ID        string
+	Extra     ExtraArgs
+	VideoHash string
+	VideoSize int64
+	Filename  string
+}
+
+// newSubtitlesArgs - builds SubtitlesArgs and parses the subtitles specific extra properties
+func newSubtitlesArgs(rp *resourcePath) (*SubtitlesArgs, error) {
+	args := &SubtitlesArgs{
+		Type:      rp.Type,
+		ID:        rp.ID,
+		Extra:     rp.Extra,
+		VideoHash: rp.Extra.Get(SubtitlesExtraVideoHash),
+		Filename:  rp.Extra.Get(SubtitlesExtraFilename),
+	}
+
+	if size := rp.Extra.Get(SubtitlesExtraVideoSize); size != "" {
+		var err error
+		if args.VideoSize, err = strconv.ParseInt(size, 10, 64); err != nil || args.VideoSize < 0 {
+			return nil, ErrInvalidPath
+		}
+	}
+
+	return args, nil
+}
+
+// resourcePath - type, id and extra parsed from the path segments following the resource name
+type resourcePath struct {
+	Type  string
+	ID    string
+	Extra ExtraArgs
+}
+
+// parseResourcePath - parses escaped {type}/{id}[/{extra}].json segments
+func parseResourcePath(segments []string) (*resourcePath, error) {
+	if len(segments) < 2 || len(segments) > 3 {
+		return nil, ErrInvalidPath
+	}
+
+	segments = append([]string(nil), segments...)
+
+	last := len(segments) - 1
+	if !strings.HasSuffix(segments[last], jsonSuffix) {
+		return nil, ErrInvalidPath
+	}
+	segments[last] = strings.TrimSuffix(segments[last], jsonSuffix)
+
+	typ, err := url.PathUnescape(segments[0])
+	if err != nil || typ == "" {
+		return nil, ErrInvalidPath
+	}
+
+	id, err := url.PathUnescape(segments[1])
+	if err != nil || id == "" {
+		return nil, ErrInvalidPath
+	}
+
+	rp := &resourcePath{Type: typ, ID: id, Extra: ExtraArgs{}}
+
+	if len(segments) == 3 {
+		if rp.Extra, err = ParseExtraArgs(segments[2]); err != nil {
+			return nil, err
+		}
+	}
+
+	return rp, nil
+}
+
+// ContentID - parses ID by ParseID
+func (a *MetaArgs) ContentID() (ContentID, error) {
+	return ParseID(a.ID)
+}
+
+// ContentID - parses ID by ParseID, e.g. season and episode of "tt0903747:1:1"
+func (a *StreamArgs) ContentID() (ContentID, error) {
+	return ParseID(a.ID)
+}
+
+// ContentID - parses ID by ParseID, e.g. season and episode of "tt0903747:1:1"
+func (a *SubtitlesArgs) ContentID() (ContentID, error) {
+	return ParseID(a.ID)
+}
@@ -0,0 +1,89 @@
+package stremigo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseResourcePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []string
+		want     *resourcePath
+		wantErr  error
+	}{
+		{
+			name:     "type and id",
+			segments: []string{TypeMovie, "top.json"},
+			want:     &resourcePath{Type: TypeMovie, ID: "top", Extra: ExtraArgs{}},
+		},
+		{
+			name:     "extra segment",
+			segments: []string{TypeMovie, "top", "genre=Action&skip=100.json"},
+			want: &resourcePath{
+				Type:  TypeMovie,
+				ID:    "top",
+				Extra: ExtraArgs{CatalogExtraGenre: {"Action"}, CatalogExtraSkip: {"100"}},
+			},
+		},
+		{
+			name:     "url encoded values",
+			segments: []string{TypeSeries, "tt0903747%3A1%3A2", "search=foo%20bar.json"},
+			want: &resourcePath{
+				Type:  TypeSeries,
+				ID:    "tt0903747:1:2",
+				Extra: ExtraArgs{CatalogExtraSearched: {"foo bar"}},
+			},
+		},
+		{
+			name:     "encoded slash in id",
+			segments: []string{TypeChannel, "yt_id%3Aa%2Fb.json"},
+			want:     &resourcePath{Type: TypeChannel, ID: "yt_id:a/b", Extra: ExtraArgs{}},
+		},
+		{
+			name:     "missing json suffix",
+			segments: []string{TypeMovie, "top"},
+			wantErr:  ErrInvalidPath,
+		},
+		{
+			name:     "missing id",
+			segments: []string{"top.json"},
+			wantErr:  ErrInvalidPath,
+		},
+		{
+			name:     "empty id",
+			segments: []string{TypeMovie, ".json"},
+			wantErr:  ErrInvalidPath,
+		},
+		{
+			name:     "too many segments",
+			segments: []string{TypeMovie, "top", "skip=100", "more.json"},
+			wantErr:  ErrInvalidPath,
+		},
+		{
+			name:     "invalid escaping",
+			segments: []string{TypeMovie, "top%zz.json"},
+			wantErr:  ErrInvalidPath,
+		},
+		{
+			name:     "invalid extra",
+			segments: []string{TypeMovie, "top", "genre=%zz.json"},
+			wantErr:  ErrInvalidPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := parseResourcePath(tt.segments)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("parseResourcePath(%q) error = %v, want %v", tt.segments, err, tt.wantErr)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("parseResourcePath(%q) = %+v, want %+v", tt.segments, got, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,155 @@
+package stremigo
+
+import (
+	"context"
+	"slices"
+)
+
+// Authenticator - verifies the token before the request is dispatched to ContextProvider; it fails with
+// ErrUnauthorized for unknown or expired tokens
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// AuthenticatorFunc - function implementing Authenticator
+type AuthenticatorFunc func(ctx context.Context, token string) (*Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	return f(ctx, token)
+}
+
+// Principal - client authenticated by Authenticator
+// ID - identifier of the client, e.g. user id
+// Policy - optional - resources, types and catalogs the client may access, nil allows everything
+// Attributes - optional - any data of the authenticator, e.g. subscription tier
+type Principal struct {
+	ID         string
+	Policy     *AccessPolicy
+	Attributes map[string]any
+}
+
+// AccessPolicy - resources the principal may access, e.g. free tier without streams; empty lists allow everything.
+// The manifest is always accessible, but lists only allowed resources and catalogs.
+// Resources - allowed resources, e.g. ResourceCatalog and ResourceMeta
+// Types - allowed content types
+// Catalogs - allowed ids of catalogs and addon catalogs
+type AccessPolicy struct {
+	Resources []string
+	Types     []string
+	Catalogs  []string
+}
+
+// Allows - reports whether the request is allowed by the policy; nil policy allows everything
+func (p *AccessPolicy) Allows(req *ResourceRequest) bool {
+	if p == nil || req.Resource == PathManifest {
+		return true
+	}
+
+	return allowed(p.Resources, req.Resource) &&
+		allowed(p.Types, req.Type) &&
+		(req.Resource != PathCatalog && req.Resource != PathAddonCatalog || allowed(p.Catalogs, req.ID))
+}
+
+// FilterManifest - returns copy of the manifest listing only resources, types and catalogs allowed by the policy
+func (p *AccessPolicy) FilterManifest(m *AddonManifest) *AddonManifest {
+	if p == nil || m == nil {
+		return m
+	}
+
+	manifest := *m
+
+	manifest.Resources = nil
+	for _, r := range m.Resources {
+		if r == nil || !allowed(p.Resources, r.Name) {
+			continue
+		}
+		if len(r.Type) > 0 && len(p.Types) > 0 {
+			resource := *r
+			resource.Type = slices.DeleteFunc(slices.Clone(r.Type), func(t string) bool { return !allowed(p.Types, t) })
+			if len(resource.Type) == 0 {
+				continue
+			}
+			r = &resource
+		}
+		manifest.Resources = append(manifest.Resources, r)
+	}
+
+	manifest.Types = slices.DeleteFunc(slices.Clone(m.Types), func(t string) bool { return !allowed(p.Types, t) })
+
+	manifest.Catalogs = p.filterCatalogs(ResourceCatalog, m.Catalogs)
+	if manifest.AddonCatalogs = p.filterCatalogs(ResourceAddonCatalog, m.AddonCatalogs); len(manifest.AddonCatalogs) == 0 {
+		manifest.AddonCatalogs = nil
+	}
+
+	return &manifest
+}
+
+// filterCatalogs - returns catalogs of the resource allowed by the policy, never nil
+func (p *AccessPolicy) filterCatalogs(resource string, catalogs []*Catalog) []*Catalog {
+	filtered := []*Catalog{}
+	if !allowed(p.Resources, resource) {
+		return filtered
+	}
+	for _, c := range catalogs {
+		if c != nil && allowed(p.Types, c.Type) && allowed(p.Catalogs, c.ID) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// allowed - reports whether the value is in the list, empty list allows every value
+func allowed(list []string, value string) bool {
+	return len(list) == 0 || slices.Contains(list, value)
+}
+
+// principalKey - context key of the authenticated Principal
+type principalKey struct{}
+
+// PrincipalFromContext - returns Principal authenticated by Authenticator of WithAuthenticator
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// WithAuthenticator - authenticates the token of every resource request, the configure page is left public;
+// the token is empty for unsecured addons. The request is answered with ErrForbidden when AccessPolicy
+// of the principal doesn't allow it, and the manifest is filtered by AccessPolicy.FilterManifest.
+// The principal is available by PrincipalFromContext.
+func WithAuthenticator(authenticator Authenticator) Option {
+	return func(s *Server) {
+		s.authenticator = authenticator
+	}
+}
+
+// authenticate - returns context carrying Principal of the token; nil principal is ErrUnauthorized
+func (s *Server) authenticate(ctx context.Context, token string) (context.Context, error) {
+	principal, err := s.authenticator.Authenticate(ctx, token)
+	if err != nil {
+		return ctx, err
+	}
+	if principal == nil {
+		return ctx, ErrUnauthorized
+	}
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// authorize - ResourceMiddleware enforcing AccessPolicy of the authenticated principal
+func authorize(next ResourceHandler) ResourceHandler {
+	return func(ctx context.Context, req *ResourceRequest) (any, error) {
+		principal, ok := PrincipalFromContext(ctx)
+		if !ok || principal.Policy == nil {
+			return next(ctx, req)
+		}
+
+		if !principal.Policy.Allows(req) {
+			return nil, ErrForbidden
+		}
+
+		data, err := next(ctx, req)
+		if manifest, ok := data.(*AddonManifest); ok && err == nil {
+			return principal.Policy.FilterManifest(manifest), nil
+		}
+		return data, err
+	}
+}
@@ -0,0 +1,177 @@
+package stremigo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newAuthServer(t *testing.T) *Server {
+	t.Helper()
+
+	catalog := func(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+		return &MetaPreviewList{Metas: []*MetaPreview{}}, nil
+	}
+
+	addon, err := NewAddonBuilder(testManifest).
+		DefineCatalogHandler(&Catalog{ID: "free", Type: TypeMovie, Name: "Free"}, catalog).
+		DefineCatalogHandler(&Catalog{ID: "premium", Type: TypeMovie, Name: "Premium"}, catalog).
+		DefineStreamHandler(
+			[]string{TypeMovie}, nil,
+			func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+				principal, _ := PrincipalFromContext(ctx)
+				return &StreamList{Streams: []*Stream{{Title: principal.ID}}}, nil
+			},
+		).
+		Secured(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	authenticator := AuthenticatorFunc(
+		func(ctx context.Context, token string) (*Principal, error) {
+			switch token {
+			case "free":
+				return &Principal{ID: "free-user", Policy: &AccessPolicy{Resources: []string{ResourceCatalog}, Catalogs: []string{"free"}}}, nil
+			case "paid":
+				return &Principal{ID: "paid-user"}, nil
+			case "nil":
+				return nil, nil
+			default:
+				return nil, ErrUnauthorized
+			}
+		},
+	)
+
+	return NewServer(addon, WithAuthenticator(authenticator))
+}
+
+func TestServerAuthenticator(t *testing.T) {
+	server := newAuthServer(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+	}{
+		{name: "unknown token", path: "/unknown/manifest.json", wantCode: http.StatusUnauthorized},
+		{name: "nil principal", path: "/nil/manifest.json", wantCode: http.StatusUnauthorized},
+		{name: "unknown token of invalid path", path: "/unknown/catalog/movie.json", wantCode: http.StatusUnauthorized},
+		{name: "configure page is public", path: "/unknown/configure", wantCode: http.StatusNotFound},
+		{name: "free manifest", path: "/free/manifest.json", wantCode: http.StatusOK},
+		{name: "free catalog", path: "/free/catalog/movie/free.json", wantCode: http.StatusOK},
+		{name: "premium catalog of free token", path: "/free/catalog/movie/premium.json", wantCode: http.StatusForbidden},
+		{name: "stream of free token", path: "/free/stream/movie/tt0111161.json", wantCode: http.StatusForbidden},
+		{name: "premium catalog", path: "/paid/catalog/movie/premium.json", wantCode: http.StatusOK},
+		{name: "stream", path: "/paid/stream/movie/tt0111161.json", wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+
+				server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+				if rr.Code != tt.wantCode {
+					t.Errorf("status = %d, want %d: %s", rr.Code, tt.wantCode, rr.Body)
+				}
+			},
+		)
+	}
+}
+
+func TestServerAuthenticatorPrincipal(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	newAuthServer(t).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/paid/stream/movie/tt0111161.json", nil))
+
+	var got StreamList
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+	if len(got.Streams) != 1 || got.Streams[0].Title != "paid-user" {
+		t.Errorf("streams = %+v, want title of the principal", got.Streams)
+	}
+}
+
+func TestServerAuthenticatorManifest(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	newAuthServer(t).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/free/manifest.json", nil))
+
+	var got AddonManifest
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+
+	if want := []*Resource{{Name: ResourceCatalog}}; !reflect.DeepEqual(got.Resources, want) {
+		t.Errorf("Resources = %+v, want %+v", got.Resources, want)
+	}
+	if len(got.Catalogs) != 1 || got.Catalogs[0].ID != "free" {
+		t.Errorf("Catalogs = %+v, want the free catalog only", got.Catalogs)
+	}
+}
+
+func TestAccessPolicyFilterManifest(t *testing.T) {
+	manifest := &AddonManifest{
+		Resources: []*Resource{{Name: ResourceCatalog}, {Name: ResourceMeta, Type: []string{TypeMovie, TypeSeries}}, {Name: ResourceStream, Type: []string{TypeSeries}}},
+		Types:     []string{TypeMovie, TypeSeries},
+		Catalogs:  []*Catalog{{ID: "top", Type: TypeMovie}, {ID: "top", Type: TypeSeries}},
+		AddonCatalogs: []*Catalog{
+			{ID: "official", Type: TypeMovie}, {ID: "community", Type: TypeMovie}, {ID: "official", Type: TypeSeries},
+		},
+	}
+	policy := &AccessPolicy{Types: []string{TypeMovie}, Catalogs: []string{"top", "official"}}
+
+	got := policy.FilterManifest(manifest)
+
+	want := &AddonManifest{
+		Resources:     []*Resource{{Name: ResourceCatalog}, {Name: ResourceMeta, Type: []string{TypeMovie}}},
+		Types:         []string{TypeMovie},
+		Catalogs:      []*Catalog{{ID: "top", Type: TypeMovie}},
+		AddonCatalogs: []*Catalog{{ID: "official", Type: TypeMovie}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterManifest() = %+v, want %+v", got, want)
+	}
+	if len(manifest.Resources[1].Type) != 2 {
+		t.Errorf("FilterManifest() modified the original manifest")
+	}
+}
+
+func TestAccessPolicyAllows(t *testing.T) {
+	policy := &AccessPolicy{Types: []string{TypeMovie}, Catalogs: []string{"official"}}
+
+	tests := []struct {
+		name string
+		req  *ResourceRequest
+		want bool
+	}{
+		{name: "manifest", req: &ResourceRequest{Resource: PathManifest}, want: true},
+		{name: "allowed catalog", req: &ResourceRequest{Resource: PathCatalog, Type: TypeMovie, ID: "official"}, want: true},
+		{name: "denied catalog", req: &ResourceRequest{Resource: PathCatalog, Type: TypeMovie, ID: "community"}},
+		{
+			name: "allowed addon catalog",
+			req:  &ResourceRequest{Resource: PathAddonCatalog, Type: TypeMovie, ID: "official"},
+			want: true,
+		},
+		{name: "denied addon catalog", req: &ResourceRequest{Resource: PathAddonCatalog, Type: TypeMovie, ID: "community"}},
+		{name: "denied type", req: &ResourceRequest{Resource: PathAddonCatalog, Type: TypeSeries, ID: "official"}},
+		{name: "meta ignores catalogs", req: &ResourceRequest{Resource: PathMeta, Type: TypeMovie, ID: "tt0111161"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := policy.Allows(tt.req); got != tt.want {
+					t.Errorf("Allows() = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
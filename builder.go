@@ -0,0 +1,322 @@
+package stremigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// CatalogHandler - handles requests of a single catalog registered by AddonBuilder.DefineCatalogHandler
+type CatalogHandler func(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error)
+
+// MetaHandler - handles meta requests registered by AddonBuilder.DefineMetaHandler
+type MetaHandler func(ctx context.Context, token string, args *MetaArgs) (*Meta, error)
+
+// StreamHandler - handles stream requests registered by AddonBuilder.DefineStreamHandler
+type StreamHandler func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error)
+
+// SubtitlesHandler - handles subtitles requests registered by AddonBuilder.DefineSubtitlesHandler
+type SubtitlesHandler func(ctx context.Context, token string, args *SubtitlesArgs) (*SubtitlesList, error)
+
+// ConfigurePageHandler - renders configure page, see ProviderInterface.RenderConfigurePage
+type ConfigurePageHandler func(w http.ResponseWriter, r *http.Request, token string)
+
+// route - handler registered for content types and optional id prefixes
+type route[H any] struct {
+	types    []string
+	prefixes []string
+	handler  H
+}
+
+// matches - reports whether the route serves content of the type with the id
+func (rt *route[H]) matches(typ, id string) bool {
+	return slices.Contains(rt.types, typ) && hasIDPrefix(rt.prefixes, id)
+}
+
+// catalogRoute - handler registered for a single catalog
+type catalogRoute struct {
+	catalog *Catalog
+	handler CatalogHandler
+}
+
+// AddonBuilder - builds Addon from handlers registered per resource, content type and id prefix;
+// equivalent of addonBuilder from the official SDK. AddonManifest.Resources, Types, Prefixes and Catalogs
+// are generated from the registrations, so the manifest never drifts apart from the dispatch logic.
+type AddonBuilder struct {
+	manifest  AddonManifest
+	catalogs  []*catalogRoute
+	meta      []*route[MetaHandler]
+	stream    []*route[StreamHandler]
+	subtitles []*route[SubtitlesHandler]
+	configure ConfigurePageHandler
+	codec     TokenCodec
+	secured   bool
+	errs      []error
+}
+
+// NewAddonBuilder - creates AddonBuilder; manifest holds the descriptive fields (ID, Version, Name, ...),
+// Types and Prefixes declared there are kept and extended by the types and prefixes of registered handlers
+func NewAddonBuilder(manifest AddonManifest) *AddonBuilder {
+	return &AddonBuilder{manifest: manifest}
+}
+
+// DefineCatalogHandler - registers handler serving the catalog; the catalog is added to AddonManifest.Catalogs
+// and the extra properties of requests are checked by ExtraArgs.Validate before the handler is called
+func (b *AddonBuilder) DefineCatalogHandler(catalog *Catalog, handler CatalogHandler) *AddonBuilder {
+	if catalog == nil || handler == nil {
+		b.errs = append(b.errs, errors.New("stremigo: catalog handler requires catalog and handler"))
+		return b
+	}
+
+	for _, c := range b.catalogs {
+		if c.catalog.Type == catalog.Type && c.catalog.ID == catalog.ID {
+			b.errs = append(b.errs, fmt.Errorf("stremigo: catalog %s/%s defined twice", catalog.Type, catalog.ID))
+			return b
+		}
+	}
+
+	b.catalogs = append(b.catalogs, &catalogRoute{catalog: catalog, handler: handler})
+	return b
+}
+
+// DefineMetaHandler - registers handler serving meta of the types, limited to ids with one of the prefixes
+// unless prefixes are empty
+func (b *AddonBuilder) DefineMetaHandler(types []string, prefixes []string, handler MetaHandler) *AddonBuilder {
+	b.meta = defineRoute(b, ResourceMeta, b.meta, types, prefixes, handler)
+	return b
+}
+
+// DefineStreamHandler - registers handler serving streams of the types, limited to ids with one of the prefixes
+// unless prefixes are empty
+func (b *AddonBuilder) DefineStreamHandler(types []string, prefixes []string, handler StreamHandler) *AddonBuilder {
+	b.stream = defineRoute(b, ResourceStream, b.stream, types, prefixes, handler)
+	return b
+}
+
+// DefineSubtitlesHandler - registers handler serving subtitles of the types, limited to ids with one of the
+// prefixes unless prefixes are empty
+func (b *AddonBuilder) DefineSubtitlesHandler(types []string, prefixes []string, handler SubtitlesHandler) *AddonBuilder {
+	b.subtitles = defineRoute(b, ResourceSubtitles, b.subtitles, types, prefixes, handler)
+	return b
+}
+
+// DefineConfigurePage - registers handler rendering the configure page; ConfigurePage generated from
+// AddonManifest.Config is used when no handler is registered
+func (b *AddonBuilder) DefineConfigurePage(handler ConfigurePageHandler) *AddonBuilder {
+	b.configure = handler
+	return b
+}
+
+// TokenCodec - encodes the token of ConfigurePage generated from AddonManifest.Config, JSONTokenCodec by default;
+// serve the addon with WithTokenCodec using the same codec
+func (b *AddonBuilder) TokenCodec(codec TokenCodec) *AddonBuilder {
+	b.codec = codec
+	return b
+}
+
+// Secured - serves the addon under /{token}/ paths, see ContextProvider.IsSecured
+func (b *AddonBuilder) Secured(secured bool) *AddonBuilder {
+	b.secured = secured
+	return b
+}
+
+// defineRoute - appends route of the handler, invalid registrations are reported by Build
+func defineRoute[H MetaHandler | StreamHandler | SubtitlesHandler](b *AddonBuilder, resource string, routes []*route[H], types, prefixes []string, handler H) []*route[H] {
+	if handler == nil {
+		b.errs = append(b.errs, fmt.Errorf("stremigo: %s handler is nil", resource))
+		return routes
+	}
+	if len(types) == 0 {
+		b.errs = append(b.errs, fmt.Errorf("stremigo: %s handler requires at least one type", resource))
+		return routes
+	}
+	return append(routes, &route[H]{types: types, prefixes: prefixes, handler: handler})
+}
+
+// Build - creates Addon with manifest generated from the registered handlers; fails with ValidationErrors
+// when the generated manifest is invalid. Addon declaring AddonManifest.Config must be Secured, its manifest
+// is marked configurable and requiring configuration when some field is required.
+func (b *AddonBuilder) Build() (*Addon, error) {
+	if len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
+	}
+	if len(b.catalogs)+len(b.meta)+len(b.stream)+len(b.subtitles) == 0 {
+		return nil, errors.New("stremigo: addon requires at least one handler")
+	}
+
+	manifest := b.manifest
+	manifest.Types = slices.Clone(manifest.Types)
+	manifest.Resources = nil
+	manifest.Catalogs = []*Catalog{}
+	manifest.Prefixes = slices.Clone(manifest.Prefixes)
+
+	if len(b.catalogs) > 0 {
+		manifest.Resources = append(manifest.Resources, &Resource{Name: ResourceCatalog})
+	}
+	for _, c := range b.catalogs {
+		manifest.Catalogs = append(manifest.Catalogs, c.catalog)
+		manifest.Types = appendUnique(manifest.Types, c.catalog.Type)
+	}
+
+	// addon-wide prefixes are generated only when every handler is limited by prefixes,
+	// prefixes declared in the manifest are kept either way
+	prefixes, limited := []string{}, true
+	for _, rs := range []*Resource{
+		routesResource(ResourceMeta, b.meta),
+		routesResource(ResourceStream, b.stream),
+		routesResource(ResourceSubtitles, b.subtitles),
+	} {
+		if rs == nil {
+			continue
+		}
+		manifest.Resources = append(manifest.Resources, rs)
+		manifest.Types = appendUnique(manifest.Types, rs.Type...)
+		prefixes = appendUnique(prefixes, rs.Prefixes...)
+		limited = limited && len(rs.Prefixes) > 0
+	}
+	if limited && len(prefixes) > 0 {
+		manifest.Prefixes = appendUnique(manifest.Prefixes, prefixes...)
+	}
+
+	configure := b.configure
+	if len(manifest.Config) > 0 {
+		// the config is encoded in the token, so the addon can't receive it unless secured
+		if !b.secured {
+			return nil, errors.New("stremigo: addon with config must be secured")
+		}
+
+		hints := AddonManifestBehaviorHints{}
+		if manifest.BehaviorHints != nil {
+			hints = *manifest.BehaviorHints
+		}
+		hints.Configurable = true
+		hints.ConfigurationRequired = hints.ConfigurationRequired ||
+			slices.ContainsFunc(manifest.Config, func(f *ConfigField) bool { return f != nil && f.Required })
+		manifest.BehaviorHints = &hints
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+
+	if configure == nil && len(manifest.Config) > 0 {
+		configure = (&ConfigurePage{Manifest: &manifest, Codec: b.codec}).RenderConfigurePage
+	}
+
+	return &Addon{
+		manifest:  &manifest,
+		catalogs:  slices.Clone(b.catalogs),
+		meta:      slices.Clone(b.meta),
+		stream:    slices.Clone(b.stream),
+		subtitles: slices.Clone(b.subtitles),
+		configure: configure,
+		secured:   b.secured,
+	}, nil
+}
+
+// routesResource - returns Resource declaring types and prefixes of the routes, nil for no routes
+func routesResource[H any](name string, routes []*route[H]) *Resource {
+	if len(routes) == 0 {
+		return nil
+	}
+
+	rs := &Resource{Name: name}
+	limited := true
+	for _, rt := range routes {
+		rs.Type = appendUnique(rs.Type, rt.types...)
+		rs.Prefixes = appendUnique(rs.Prefixes, rt.prefixes...)
+		limited = limited && len(rt.prefixes) > 0
+	}
+	if !limited {
+		rs.Prefixes = nil
+	}
+	return rs
+}
+
+// appendUnique - appends values not yet present in the slice
+func appendUnique(s []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(s, v) {
+			s = append(s, v)
+		}
+	}
+	return s
+}
+
+// Addon - ContextProvider dispatching requests to handlers registered in AddonBuilder
+type Addon struct {
+	manifest  *AddonManifest
+	catalogs  []*catalogRoute
+	meta      []*route[MetaHandler]
+	stream    []*route[StreamHandler]
+	subtitles []*route[SubtitlesHandler]
+	configure ConfigurePageHandler
+	secured   bool
+}
+
+// matchRoute - returns handler of the first route serving content of the type with the id
+func matchRoute[H any](routes []*route[H], typ, id string) (H, bool) {
+	for _, rt := range routes {
+		if rt.matches(typ, id) {
+			return rt.handler, true
+		}
+	}
+	var zero H
+	return zero, false
+}
+
+// Manifest - returns the generated manifest
+func (a *Addon) Manifest(ctx context.Context, token string) (*AddonManifest, error) {
+	manifest := *a.manifest
+	return &manifest, nil
+}
+
+func (a *Addon) Catalog(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+	for _, c := range a.catalogs {
+		if c.catalog.Type == args.Type && c.catalog.ID == args.ID {
+			if err := args.Extra.Validate(c.catalog); err != nil {
+				return nil, err
+			}
+			return c.handler(ctx, token, args)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (a *Addon) Meta(ctx context.Context, token string, args *MetaArgs) (*Meta, error) {
+	handler, ok := matchRoute(a.meta, args.Type, args.ID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return handler(ctx, token, args)
+}
+
+func (a *Addon) Stream(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+	handler, ok := matchRoute(a.stream, args.Type, args.ID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return handler(ctx, token, args)
+}
+
+func (a *Addon) Subtitles(ctx context.Context, token string, args *SubtitlesArgs) (*SubtitlesList, error) {
+	handler, ok := matchRoute(a.subtitles, args.Type, args.ID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return handler(ctx, token, args)
+}
+
+func (a *Addon) RenderConfigurePage(w http.ResponseWriter, r *http.Request, token string) {
+	if a.configure == nil {
+		WriteErrorResponse(w, NewErrorResponse(ErrNotFound, DefaultErrorMessages))
+		return
+	}
+	a.configure(w, r, token)
+}
+
+func (a *Addon) IsSecured() bool {
+	return a.secured
+}
@@ -0,0 +1,264 @@
+package stremigo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+var testManifest = AddonManifest{
+	ID:          "com.example.builder",
+	Version:     "1.0.0",
+	Name:        "Builder",
+	Description: "Addon built in tests",
+}
+
+func newTestBuilder() *AddonBuilder {
+	return NewAddonBuilder(testManifest).
+		DefineCatalogHandler(
+			&Catalog{ID: "top", Type: TypeMovie, Name: "Top movies"},
+			func(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+				return &MetaPreviewList{Metas: []*MetaPreview{{ID: "tt0111161", Type: TypeMovie, Name: "Top"}}}, nil
+			},
+		).
+		DefineMetaHandler(
+			[]string{TypeMovie, TypeSeries}, []string{PrefixImdb},
+			func(ctx context.Context, token string, args *MetaArgs) (*Meta, error) {
+				return &Meta{ID: args.ID, Type: args.Type, Name: "imdb"}, nil
+			},
+		).
+		DefineMetaHandler(
+			[]string{TypeChannel}, []string{PrefixYoutube},
+			func(ctx context.Context, token string, args *MetaArgs) (*Meta, error) {
+				return &Meta{ID: args.ID, Type: args.Type, Name: "youtube"}, nil
+			},
+		).
+		DefineStreamHandler(
+			[]string{TypeMovie}, nil,
+			func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+				return &StreamList{Streams: []*Stream{{URL: "https://example.com/" + args.ID + ".mp4"}}}, nil
+			},
+		)
+}
+
+func TestAddonBuilderManifest(t *testing.T) {
+	addon, err := newTestBuilder().Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	got, err := addon.Manifest(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Manifest() error = %v", err)
+	}
+
+	wantResources := []*Resource{
+		{Name: ResourceCatalog},
+		{Name: ResourceMeta, Type: []string{TypeMovie, TypeSeries, TypeChannel}, Prefixes: []string{PrefixImdb, PrefixYoutube}},
+		{Name: ResourceStream, Type: []string{TypeMovie}},
+	}
+	if !reflect.DeepEqual(got.Resources, wantResources) {
+		t.Errorf("Resources = %+v, want %+v", got.Resources, wantResources)
+	}
+	if want := []string{TypeMovie, TypeSeries, TypeChannel}; !reflect.DeepEqual(got.Types, want) {
+		t.Errorf("Types = %v, want %v", got.Types, want)
+	}
+	if got.Prefixes != nil {
+		t.Errorf("Prefixes = %v, want nil as stream handler accepts every id", got.Prefixes)
+	}
+	if len(got.Catalogs) != 1 || got.Catalogs[0].ID != "top" {
+		t.Errorf("Catalogs = %+v, want the top catalog", got.Catalogs)
+	}
+}
+
+func TestAddonBuilderPrefixes(t *testing.T) {
+	addon, err := NewAddonBuilder(testManifest).
+		DefineStreamHandler(
+			[]string{TypeMovie}, []string{PrefixImdb},
+			func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+				return &StreamList{}, nil
+			},
+		).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	got, _ := addon.Manifest(context.Background(), "")
+	if want := []string{PrefixImdb}; !reflect.DeepEqual(got.Prefixes, want) {
+		t.Errorf("Prefixes = %v, want %v", got.Prefixes, want)
+	}
+
+	manifest := testManifest
+	manifest.Prefixes = []string{PrefixKitsu}
+	addon, err = NewAddonBuilder(manifest).
+		DefineStreamHandler(
+			[]string{TypeMovie}, []string{PrefixImdb},
+			func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+				return &StreamList{}, nil
+			},
+		).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	got, _ = addon.Manifest(context.Background(), "")
+	if want := []string{PrefixKitsu, PrefixImdb}; !reflect.DeepEqual(got.Prefixes, want) {
+		t.Errorf("Prefixes = %v, want %v including the declared ones", got.Prefixes, want)
+	}
+}
+
+func TestAddonBuilderErrors(t *testing.T) {
+	catalog := func(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+		return nil, nil
+	}
+
+	tests := []struct {
+		name    string
+		builder *AddonBuilder
+	}{
+		{
+			name:    "no handlers",
+			builder: NewAddonBuilder(AddonManifest{}),
+		},
+		{
+			name: "duplicate catalog",
+			builder: NewAddonBuilder(AddonManifest{}).
+				DefineCatalogHandler(&Catalog{ID: "top", Type: TypeMovie}, catalog).
+				DefineCatalogHandler(&Catalog{ID: "top", Type: TypeMovie}, catalog),
+		},
+		{
+			name: "invalid manifest",
+			builder: NewAddonBuilder(AddonManifest{ID: "builder"}).
+				DefineCatalogHandler(&Catalog{ID: "top", Type: TypeMovie, Name: "Top"}, catalog),
+		},
+		{
+			name: "missing types",
+			builder: NewAddonBuilder(AddonManifest{}).
+				DefineMetaHandler(
+					nil, nil, func(ctx context.Context, token string, args *MetaArgs) (*Meta, error) {
+						return nil, nil
+					},
+				),
+		},
+		{
+			name: "nil handler",
+			builder: NewAddonBuilder(AddonManifest{}).
+				DefineMetaHandler([]string{TypeMovie}, nil, nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if _, err := tt.builder.Build(); err == nil {
+					t.Error("Build() error = nil, want error")
+				}
+			},
+		)
+	}
+}
+
+func TestAddonDispatch(t *testing.T) {
+	addon, err := newTestBuilder().Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantName string
+	}{
+		{
+			name:     "catalog",
+			path:     "/catalog/movie/top.json",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "catalog with undeclared extra",
+			path:     "/catalog/movie/top/genre=Action.json",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unknown catalog",
+			path:     "/catalog/series/top.json",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "meta by imdb prefix",
+			path:     "/meta/series/tt0903747.json",
+			wantCode: http.StatusOK,
+			wantName: "imdb",
+		},
+		{
+			name:     "meta by youtube prefix",
+			path:     "/meta/channel/yt_id:UCrDkAvwZum-UTjHmzDI2iIw.json",
+			wantCode: http.StatusOK,
+			wantName: "youtube",
+		},
+		{
+			name:     "meta with unknown prefix",
+			path:     "/meta/movie/kitsu:1.json",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "meta with unknown type",
+			path:     "/meta/tv/tt0903747.json",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "stream without prefixes",
+			path:     "/stream/movie/anything.json",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "subtitles without handler",
+			path:     "/subtitles/movie/tt0111161.json",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "configure page without handler",
+			path:     "/" + PathConfigure,
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+
+				ContextRouter(rr, httptest.NewRequest(http.MethodGet, tt.path, nil), addon)
+
+				if rr.Code != tt.wantCode {
+					t.Fatalf("ContextRouter(%q) status = %d, want %d", tt.path, rr.Code, tt.wantCode)
+				}
+				if tt.wantName == "" {
+					if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+						t.Errorf("ContextRouter(%q) Content-Type = %q, want the JSON error envelope", tt.path, ct)
+					}
+					return
+				}
+
+				var meta Meta
+				if err := json.NewDecoder(rr.Body).Decode(&meta); err != nil {
+					t.Fatalf("decoding response: %v", err)
+				}
+				if meta.Name != tt.wantName {
+					t.Errorf("ContextRouter(%q) meta name = %q, want %q", tt.path, meta.Name, tt.wantName)
+				}
+			},
+		)
+	}
+
+	if _, err := addon.Subtitles(context.Background(), "", &SubtitlesArgs{Type: TypeMovie, ID: "tt0111161"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Subtitles() error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,393 @@
+package stremigo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheMaxEntries - how many responses the default MemoryStore of ResponseCache keeps
+const DefaultCacheMaxEntries = 10000
+
+// DefaultCacheRevalidateTimeout - deadline of the background refresh of a stale response
+const DefaultCacheRevalidateTimeout = 30 * time.Second
+
+// CacheScope - returns the scope of the request; responses are shared only by requests of the same scope
+type CacheScope func(ctx context.Context, req *ResourceRequest) string
+
+// CacheOption - configures ResponseCache created by NewResponseCache
+type CacheOption func(c *ResponseCache)
+
+// ResponseCache - cache of provider responses in CacheStore, see WithResponseCache. Responses are fresh for their
+// CacheMaxAge, then served for StaleRevalidate while they are revalidated in the background, and for StaleError
+// when the provider fails. Concurrent requests missing the cache are served by a single provider call.
+type ResponseCache struct {
+	store      CacheStore
+	scope      CacheScope
+	maxEntries int
+	timeout    time.Duration
+	logger     *slog.Logger
+	now        func() time.Time
+
+	mu    sync.Mutex
+	calls map[string]*cacheCall
+}
+
+// cacheEntry - cached response encoded as JSON with ETag and Last-Modified set by the provider, the form kept
+// in CacheStore
+type cacheEntry struct {
+	Body         json.RawMessage `json:"body"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified time.Time       `json:"lastModified,omitzero"`
+	Stored       time.Time       `json:"stored"`
+	Policy       CachePolicy     `json:"policy"`
+}
+
+// cacheCall - provider call shared by concurrent requests of the same key
+type cacheCall struct {
+	done  chan struct{}
+	entry *cacheEntry
+	err   error
+}
+
+// NewResponseCache - creates ResponseCache of responses scoped by TokenCacheScope, kept in MemoryStore
+// of DefaultCacheMaxEntries unless WithCacheStore is used
+func NewResponseCache(opts ...CacheOption) *ResponseCache {
+	c := &ResponseCache{
+		scope:      TokenCacheScope,
+		maxEntries: DefaultCacheMaxEntries,
+		timeout:    DefaultCacheRevalidateTimeout,
+		now:        time.Now,
+		calls:      map[string]*cacheCall{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.store == nil && c.maxEntries > 0 {
+		c.store = NewMemoryStore(c.maxEntries)
+	}
+
+	return c
+}
+
+// WithCacheStore - keeps the responses in the store instead of MemoryStore, e.g. in FileStore to outlive restarts
+// of a single process, or in RedisStore to share them across replicas of the addon
+func WithCacheStore(store CacheStore) CacheOption {
+	return func(c *ResponseCache) {
+		c.store = store
+	}
+}
+
+// WithCacheScope - replaces TokenCacheScope, e.g. by SharedCacheScope when the responses don't depend on the token
+func WithCacheScope(scope CacheScope) CacheOption {
+	return func(c *ResponseCache) {
+		c.scope = scope
+	}
+}
+
+// WithCacheMaxEntries - replaces DefaultCacheMaxEntries of the default MemoryStore, ignored with WithCacheStore;
+// zero disables caching, concurrent requests missing the cache are still served by a single provider call
+func WithCacheMaxEntries(n int) CacheOption {
+	return func(c *ResponseCache) {
+		c.maxEntries = n
+	}
+}
+
+// WithCacheRevalidateTimeout - replaces DefaultCacheRevalidateTimeout; requests of the key wait for the refresh
+// in progress, so a hanging provider holds them no longer than the timeout
+func WithCacheRevalidateTimeout(timeout time.Duration) CacheOption {
+	return func(c *ResponseCache) {
+		c.timeout = timeout
+	}
+}
+
+// WithCacheLogger - logs failures of CacheStore, the cache is bypassed then
+func WithCacheLogger(logger *slog.Logger) CacheOption {
+	return func(c *ResponseCache) {
+		c.logger = logger
+	}
+}
+
+// Len - returns the number of cached responses, including expired ones not evicted yet; -1 when the store
+// can't count its values, e.g. RedisStore shared with other data
+func (c *ResponseCache) Len() int {
+	if c.store == nil {
+		return 0
+	}
+	if counter, ok := c.store.(interface{ Len() int }); ok {
+		return counter.Len()
+	}
+	return -1
+}
+
+// TokenCacheScope - CacheScope sharing responses only by requests of the same token
+func TokenCacheScope(ctx context.Context, req *ResourceRequest) string {
+	return req.Token
+}
+
+// SharedCacheScope - CacheScope sharing responses by all requests regardless of the token
+func SharedCacheScope(ctx context.Context, req *ResourceRequest) string {
+	return ""
+}
+
+// WithResponseCache - caches provider responses by the cache; it is the innermost ResourceMiddleware,
+// so requests refused by authorization or the ID filter don't reach it. Responses setting no cache policy
+// use the default of WithCachePolicy, responses without max age are not cached.
+func WithResponseCache(cache *ResponseCache) Option {
+	return func(s *Server) {
+		s.responseCache = cache
+	}
+}
+
+// middleware - returns ResourceMiddleware caching responses; defaults are used for responses setting no policy
+func (c *ResponseCache) middleware(defaults map[string]CachePolicy) ResourceMiddleware {
+	return func(next ResourceHandler) ResourceHandler {
+		return func(ctx context.Context, req *ResourceRequest) (any, error) {
+			key := c.key(ctx, req)
+			fetch := func(ctx context.Context) (*cacheEntry, error) {
+				data, err := next(ctx, req)
+				if err != nil || data == nil {
+					return nil, err
+				}
+				body, err := json.Marshal(data)
+				if err != nil {
+					return nil, err
+				}
+				etag, lastModified := validatorsFromContext(ctx).get()
+				return &cacheEntry{
+					Body:         body,
+					ETag:         etag,
+					LastModified: lastModified,
+					Policy:       responseCachePolicy(defaults, req.Resource, data),
+				}, nil
+			}
+
+			entry, now := c.get(ctx, key), c.now()
+			if entry != nil && entry.fresh(now) {
+				return entry.response(ctx, req.Resource)
+			}
+			if entry != nil && entry.revalidating(now) {
+				c.revalidate(ctx, key, fetch)
+				return entry.response(ctx, req.Resource)
+			}
+
+			loaded, err := c.load(ctx, key, fetch)
+			if err != nil && entry != nil && entry.staleOnError(now) && StatusCode(err) >= http.StatusInternalServerError {
+				return entry.response(ctx, req.Resource)
+			}
+			if err != nil || loaded == nil {
+				return nil, err
+			}
+			return loaded.response(ctx, req.Resource)
+		}
+	}
+}
+
+// key - returns the cache key of the request
+func (c *ResponseCache) key(ctx context.Context, req *ResourceRequest) string {
+	return strings.Join([]string{c.scope(ctx, req), req.Resource, req.Type, req.ID, req.Extra.Encode()}, "\x00")
+}
+
+// load - calls fetch once for concurrent requests of the key and caches the response; requests joining
+// the call of a legacy provider, which wrote the response itself, or of a cancelled request call fetch on their own
+func (c *ResponseCache) load(ctx context.Context, key string, fetch func(ctx context.Context) (*cacheEntry, error)) (*cacheEntry, error) {
+	call, leader := c.begin(key)
+	if leader {
+		c.run(ctx, key, call, fetch)
+		return call.entry, call.err
+	}
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if errors.Is(call.err, errResponseWritten) || errors.Is(call.err, context.Canceled) {
+		return fetch(ctx)
+	}
+	return call.entry, call.err
+}
+
+// revalidate - refreshes the stale response in the background unless it is already being loaded
+func (c *ResponseCache) revalidate(ctx context.Context, key string, fetch func(ctx context.Context) (*cacheEntry, error)) {
+	call, leader := c.begin(key)
+	if !leader {
+		return
+	}
+
+	// the refresh outlives the request, legacy providers write their response nowhere and validators
+	// set by the provider don't reach the served response
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	ctx = withValidators(ctx)
+	if e := exchangeFromContext(ctx); e != nil {
+		ctx = withExchange(ctx, discardResponseWriter{}, e.r.WithContext(ctx))
+	}
+	go func() {
+		defer cancel()
+		c.run(ctx, key, call, fetch)
+	}()
+}
+
+// begin - returns the call of the key in progress, or registers a new one and reports the caller leads it
+func (c *ResponseCache) begin(key string) (*cacheCall, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if call, ok := c.calls[key]; ok {
+		return call, false
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.calls[key] = call
+	return call, true
+}
+
+// run - calls fetch, caches the response and releases requests waiting for the call
+func (c *ResponseCache) run(ctx context.Context, key string, call *cacheCall, fetch func(ctx context.Context) (*cacheEntry, error)) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	entry, err := fetch(ctx)
+	if err != nil || entry == nil {
+		call.err = err
+		return
+	}
+
+	call.entry = entry
+	entry.Stored = c.now()
+	c.set(ctx, key, entry)
+}
+
+// get - returns the entry of the key, nil when it is missing, expired or the store fails
+func (c *ResponseCache) get(ctx context.Context, key string) *cacheEntry {
+	if c.store == nil {
+		return nil
+	}
+
+	value, err := c.store.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, ErrCacheMiss) {
+			c.log(ctx, "get", err)
+		}
+		return nil
+	}
+
+	entry := &cacheEntry{}
+	if err = json.Unmarshal(value, entry); err != nil {
+		c.log(ctx, "get", err)
+		return nil
+	}
+	if entry.expired(c.now()) {
+		return nil
+	}
+	return entry
+}
+
+// set - stores the entry until it may no longer be served, unless its policy has no max age or caching is disabled
+func (c *ResponseCache) set(ctx context.Context, key string, entry *cacheEntry) {
+	if entry.Policy.MaxAge <= 0 || c.store == nil {
+		return
+	}
+
+	value, err := json.Marshal(entry)
+	if err == nil {
+		ttl := seconds(entry.Policy.MaxAge + max(entry.Policy.StaleRevalidate, entry.Policy.StaleError))
+		err = c.store.Set(ctx, key, value, ttl)
+	}
+	if err != nil {
+		c.log(ctx, "set", err)
+	}
+}
+
+// log - logs failure of the store operation
+func (c *ResponseCache) log(ctx context.Context, op string, err error) {
+	if c.logger != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "stremigo cache store failed", slog.String("operation", op), slog.Any("error", err))
+	}
+}
+
+// response - decodes the cached response and passes its ETag bound to it and Last-Modified to Router
+func (e *cacheEntry) response(ctx context.Context, resource string) (any, error) {
+	SetETag(ctx, e.ETag)
+	SetLastModified(ctx, e.LastModified)
+	validatorsFromContext(ctx).bind(e.Body)
+	return decodeCached(resource, e.Body)
+}
+
+// age - returns how long ago the entry was stored
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	return now.Sub(e.Stored)
+}
+
+// fresh - reports whether the entry is within its max age
+func (e *cacheEntry) fresh(now time.Time) bool {
+	return e.age(now) < seconds(e.Policy.MaxAge)
+}
+
+// revalidating - reports whether the stale entry may be served while it is revalidated
+func (e *cacheEntry) revalidating(now time.Time) bool {
+	return e.age(now) < seconds(e.Policy.MaxAge+e.Policy.StaleRevalidate)
+}
+
+// staleOnError - reports whether the stale entry may be served when the provider fails
+func (e *cacheEntry) staleOnError(now time.Time) bool {
+	return e.age(now) < seconds(e.Policy.MaxAge+e.Policy.StaleError)
+}
+
+// expired - reports whether the entry may no longer be served at all
+func (e *cacheEntry) expired(now time.Time) bool {
+	return !e.revalidating(now) && !e.staleOnError(now)
+}
+
+func seconds(s int) time.Duration {
+	return time.Duration(s) * time.Second
+}
+
+// decodeCached - decodes cached response to the typed result of the resource, see ResourceHandler
+func decodeCached(resource string, body []byte) (any, error) {
+	var v any
+	switch resource {
+	case PathManifest:
+		v = &AddonManifest{}
+	case PathAddonCatalog:
+		v = &AddonCatalogList{}
+	case PathCatalog:
+		v = &MetaPreviewList{}
+	case PathMeta:
+		v = &Meta{}
+	case PathStream:
+		v = &StreamList{}
+	case PathSubtitles:
+		v = &SubtitlesList{}
+	default:
+		return nil, ErrNotFound
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// discardResponseWriter - http.ResponseWriter of background revalidation, the response is dropped
+type discardResponseWriter struct{}
+
+func (discardResponseWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (discardResponseWriter) WriteHeader(int) {}
@@ -0,0 +1,295 @@
+package stremigo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClock - clock of ResponseCache moved by the test
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// cachedStreams - stream handler titling the streams by the call number, failing with err when set
+type cachedStreams struct {
+	calls   atomic.Int32
+	err     atomic.Pointer[error]
+	release chan struct{}
+	hang    atomic.Bool
+	policy  CachePolicy
+}
+
+func (s *cachedStreams) Stream(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+	n := s.calls.Add(1)
+	if s.hang.Load() {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if s.release != nil {
+		<-s.release
+	}
+	if err := s.err.Load(); err != nil {
+		return nil, *err
+	}
+	return &StreamList{
+		Streams:         []*Stream{{Title: strconv.Itoa(int(n))}},
+		CacheMaxAge:     s.policy.MaxAge,
+		StaleRevalidate: s.policy.StaleRevalidate,
+		StaleError:      s.policy.StaleError,
+	}, nil
+}
+
+// newCachedServer - serves the streams of secured addon cached by the cache
+func newCachedServer(t *testing.T, streams *cachedStreams, cache *ResponseCache) *Server {
+	t.Helper()
+
+	addon, err := NewAddonBuilder(testManifest).
+		DefineStreamHandler([]string{TypeMovie}, nil, streams.Stream).
+		Secured(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	return NewServer(addon, WithResponseCache(cache))
+}
+
+// getStreamTitle - requests the streams and returns the status and the title of the first stream
+func getStreamTitle(s *Server, path string) (int, string) {
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var list StreamList
+	if json.NewDecoder(rr.Body).Decode(&list) != nil || len(list.Streams) == 0 {
+		return rr.Code, ""
+	}
+	return rr.Code, list.Streams[0].Title
+}
+
+func TestResponseCacheStale(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	cache := NewResponseCache()
+	cache.now = clock.Now
+
+	streams := &cachedStreams{policy: CachePolicy{MaxAge: 60, StaleRevalidate: 60, StaleError: 600}}
+	s := newCachedServer(t, streams, cache)
+	path := "/token/stream/movie/tt0111161.json"
+
+	if code, title := getStreamTitle(s, path); code != http.StatusOK || title != "1" {
+		t.Fatalf("first response = %d %q, want 200 \"1\"", code, title)
+	}
+
+	clock.Add(30 * time.Second)
+	if _, title := getStreamTitle(s, path); title != "1" || streams.calls.Load() != 1 {
+		t.Errorf("fresh response = %q after %d calls, want \"1\" after 1 call", title, streams.calls.Load())
+	}
+
+	clock.Add(60 * time.Second)
+	if _, title := getStreamTitle(s, path); title != "1" {
+		t.Errorf("stale response = %q, want \"1\" served while revalidating", title)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, title := getStreamTitle(s, path); title == "2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("revalidated response not cached")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	failure := ErrUpstreamUnavailable
+	streams.err.Store(&failure)
+
+	clock.Add(200 * time.Second)
+	if code, title := getStreamTitle(s, path); code != http.StatusOK || title != "2" {
+		t.Errorf("response of failing provider = %d %q, want 200 \"2\" served stale on error", code, title)
+	}
+	if got := streams.calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+
+	clock.Add(time.Hour)
+	if code, _ := getStreamTitle(s, path); code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d after stale-if-error expired", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestResponseCacheRevalidateTimeout(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	cache := NewResponseCache(WithCacheRevalidateTimeout(20 * time.Millisecond))
+	cache.now = clock.Now
+
+	streams := &cachedStreams{policy: CachePolicy{MaxAge: 60, StaleRevalidate: 60}}
+	s := newCachedServer(t, streams, cache)
+	path := "/token/stream/movie/tt0111161.json"
+
+	getStreamTitle(s, path)
+	streams.hang.Store(true)
+	clock.Add(90 * time.Second)
+	if _, title := getStreamTitle(s, path); title != "1" {
+		t.Fatalf("stale response = %q, want \"1\" served while revalidating", title)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		cache.mu.Lock()
+		calls := len(cache.calls)
+		cache.mu.Unlock()
+		if calls == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("refresh of the hanging provider still in progress, want it timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestResponseCacheCollapsesMisses(t *testing.T) {
+	streams := &cachedStreams{release: make(chan struct{}), policy: CachePolicy{MaxAge: 60}}
+	s := newCachedServer(t, streams, NewResponseCache())
+
+	var wg sync.WaitGroup
+	titles := make([]string, 10)
+	for i := range titles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, titles[i] = getStreamTitle(s, "/token/stream/movie/tt0111161.json")
+		}()
+	}
+
+	for streams.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(streams.release)
+	wg.Wait()
+
+	if got := streams.calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1 for concurrent identical requests", got)
+	}
+	for i, title := range titles {
+		if title != "1" {
+			t.Errorf("response %d = %q, want \"1\"", i, title)
+		}
+	}
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []CacheOption
+		policy    CachePolicy
+		paths     []string
+		wantCalls int32
+		wantLen   int
+	}{
+		{
+			name:      "same request",
+			policy:    CachePolicy{MaxAge: 60},
+			paths:     []string{"/a/stream/movie/tt1.json", "/a/stream/movie/tt1.json"},
+			wantCalls: 1,
+			wantLen:   1,
+		},
+		{
+			name:      "other id",
+			policy:    CachePolicy{MaxAge: 60},
+			paths:     []string{"/a/stream/movie/tt1.json", "/a/stream/movie/tt2.json"},
+			wantCalls: 2,
+			wantLen:   2,
+		},
+		{
+			name:      "other extra",
+			policy:    CachePolicy{MaxAge: 60},
+			paths:     []string{"/a/stream/movie/tt1.json", "/a/stream/movie/tt1/genre=Drama.json"},
+			wantCalls: 2,
+			wantLen:   2,
+		},
+		{
+			name:      "other token",
+			policy:    CachePolicy{MaxAge: 60},
+			paths:     []string{"/a/stream/movie/tt1.json", "/b/stream/movie/tt1.json"},
+			wantCalls: 2,
+			wantLen:   2,
+		},
+		{
+			name:      "shared scope",
+			opts:      []CacheOption{WithCacheScope(SharedCacheScope)},
+			policy:    CachePolicy{MaxAge: 60},
+			paths:     []string{"/a/stream/movie/tt1.json", "/b/stream/movie/tt1.json"},
+			wantCalls: 1,
+			wantLen:   1,
+		},
+		{
+			name:      "no max age",
+			policy:    CachePolicy{StaleError: 60},
+			paths:     []string{"/a/stream/movie/tt1.json", "/a/stream/movie/tt1.json"},
+			wantCalls: 2,
+			wantLen:   0,
+		},
+		{
+			name:      "disabled",
+			opts:      []CacheOption{WithCacheMaxEntries(0)},
+			policy:    CachePolicy{MaxAge: 60},
+			paths:     []string{"/a/stream/movie/tt1.json", "/a/stream/movie/tt1.json"},
+			wantCalls: 2,
+			wantLen:   0,
+		},
+		{
+			name:      "evicted",
+			opts:      []CacheOption{WithCacheMaxEntries(1)},
+			policy:    CachePolicy{MaxAge: 60},
+			paths:     []string{"/a/stream/movie/tt1.json", "/a/stream/movie/tt2.json", "/a/stream/movie/tt1.json"},
+			wantCalls: 3,
+			wantLen:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				streams := &cachedStreams{policy: tt.policy}
+				cache := NewResponseCache(tt.opts...)
+				s := newCachedServer(t, streams, cache)
+
+				for _, path := range tt.paths {
+					if code, _ := getStreamTitle(s, path); code != http.StatusOK {
+						t.Fatalf("GET %s status = %d", path, code)
+					}
+				}
+				if got := streams.calls.Load(); got != tt.wantCalls {
+					t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+				}
+				if got := cache.Len(); got != tt.wantLen {
+					t.Errorf("Len() = %d, want %d", got, tt.wantLen)
+				}
+			},
+		)
+	}
+
+	if got := NewResponseCache(WithCacheStore(NewRedisStore("localhost:6379"))).Len(); got != -1 {
+		t.Errorf("Len() of RedisStore = %d, want -1", got)
+	}
+}
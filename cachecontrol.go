@@ -0,0 +1,101 @@
+package stremigo
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CachePolicy - HTTP caching of a response, all values in seconds; zero values are omitted
+// MaxAge - max-age directive, max age of the cache
+// StaleRevalidate - stale-while-revalidate directive, how long a stale response may be served while revalidating
+// StaleError - stale-if-error directive, how long a stale response may be served when revalidation fails
+type CachePolicy struct {
+	MaxAge          int
+	StaleRevalidate int
+	StaleError      int
+}
+
+// IsZero - reports whether no directive is set
+func (c CachePolicy) IsZero() bool {
+	return c == CachePolicy{}
+}
+
+// Header - returns Cache-Control header value of a response shared by all users, e.g.
+// "max-age=3600, stale-while-revalidate=600, public"; empty string for zero policy
+func (c CachePolicy) Header() string {
+	return c.header("public")
+}
+
+// PrivateHeader - returns Cache-Control header value of a response of a single user, e.g. the response
+// to a token of a secured addon, which shared caches and CDNs must not store; empty string for zero policy
+func (c CachePolicy) PrivateHeader() string {
+	return c.header("private")
+}
+
+// header - returns Cache-Control header value with the scope directive, public or private
+func (c CachePolicy) header(scope string) string {
+	if c.IsZero() {
+		return ""
+	}
+
+	var directives []string
+	if c.MaxAge > 0 {
+		directives = append(directives, "max-age="+strconv.Itoa(c.MaxAge))
+	}
+	if c.StaleRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+strconv.Itoa(c.StaleRevalidate))
+	}
+	if c.StaleError > 0 {
+		directives = append(directives, "stale-if-error="+strconv.Itoa(c.StaleError))
+	}
+	return strings.Join(append(directives, scope), ", ")
+}
+
+// cacheable - response carrying its own CachePolicy
+type cacheable interface {
+	CachePolicy() CachePolicy
+}
+
+// CachePolicy - returns CacheMaxAge, StaleRevalidate and StaleError of the list
+func (l *MetaList) CachePolicy() CachePolicy {
+	return CachePolicy{MaxAge: l.CacheMaxAge, StaleRevalidate: l.StaleRevalidate, StaleError: l.StaleError}
+}
+
+// CachePolicy - returns CacheMaxAge, StaleRevalidate and StaleError of the list
+func (l *MetaPreviewList) CachePolicy() CachePolicy {
+	return CachePolicy{MaxAge: l.CacheMaxAge, StaleRevalidate: l.StaleRevalidate, StaleError: l.StaleError}
+}
+
+// CachePolicy - returns CacheMaxAge, StaleRevalidate and StaleError of the list
+func (l *StreamList) CachePolicy() CachePolicy {
+	return CachePolicy{MaxAge: l.CacheMaxAge, StaleRevalidate: l.StaleRevalidate, StaleError: l.StaleError}
+}
+
+// CachePolicy - returns CacheMaxAge, StaleRevalidate and StaleError of the list
+func (l *SubtitlesList) CachePolicy() CachePolicy {
+	return CachePolicy{MaxAge: l.CacheMaxAge, StaleRevalidate: l.StaleRevalidate, StaleError: l.StaleError}
+}
+
+// responseCachePolicy - returns policy of the response, or the default of the resource when the response
+// sets none
+func responseCachePolicy(defaults map[string]CachePolicy, resource string, data any) CachePolicy {
+	if c, ok := data.(cacheable); ok {
+		if policy := c.CachePolicy(); !policy.IsZero() {
+			return policy
+		}
+	}
+	return defaults[resource]
+}
+
+// setCacheControl - sets Cache-Control header for the response of the resource; responses to a token are private
+func setCacheControl(w http.ResponseWriter, defaults map[string]CachePolicy, req *ResourceRequest, data any) {
+	policy := responseCachePolicy(defaults, req.Resource, data)
+	header := policy.Header()
+	if req.Token != "" {
+		header = policy.PrivateHeader()
+	}
+	if header != "" {
+		w.Header().Set("Cache-Control", header)
+	}
+}
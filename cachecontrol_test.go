@@ -0,0 +1,90 @@
+package stremigo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCachePolicyHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy CachePolicy
+		want   string
+	}{
+		{name: "zero", policy: CachePolicy{}, want: ""},
+		{name: "max age", policy: CachePolicy{MaxAge: 3600}, want: "max-age=3600, public"},
+		{
+			name:   "all directives",
+			policy: CachePolicy{MaxAge: 3600, StaleRevalidate: 600, StaleError: 86400},
+			want:   "max-age=3600, stale-while-revalidate=600, stale-if-error=86400, public",
+		},
+		{name: "stale only", policy: CachePolicy{StaleError: 60}, want: "stale-if-error=60, public"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := tt.policy.Header(); got != tt.want {
+					t.Errorf("Header() = %q, want %q", got, tt.want)
+				}
+				if got, want := tt.policy.PrivateHeader(), strings.Replace(tt.want, "public", "private", 1); got != want {
+					t.Errorf("PrivateHeader() = %q, want %q", got, want)
+				}
+			},
+		)
+	}
+}
+
+func TestServerCacheControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		secured bool
+		path    string
+		want    string
+	}{
+		{name: "manifest default", path: "/" + PathManifest, want: "max-age=86400, public"},
+		{name: "catalog default", path: "/catalog/movie/top.json", want: "max-age=600, stale-while-revalidate=60, public"},
+		{name: "response overrides default", path: "/subtitles/movie/tt0111161.json", want: "max-age=3600, public"},
+		{name: "no policy", path: "/stream/movie/tt0111161.json", want: ""},
+		{name: "no policy for errors", path: "/meta/movie/tt0111161.json", want: ""},
+		{name: "token is private", secured: true, path: "/token/" + PathManifest, want: "max-age=86400, private"},
+		{
+			name:    "token response is private",
+			secured: true,
+			path:    "/token/subtitles/movie/tt0111161.json",
+			want:    "max-age=3600, private",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+
+				provider := &errorMetaProvider{mockProvider{secured: tt.secured}}
+				NewServer(
+					AdaptProvider(provider),
+					WithCachePolicy(PathManifest, CachePolicy{MaxAge: 86400}),
+					WithCachePolicy(PathCatalog, CachePolicy{MaxAge: 600, StaleRevalidate: 60}),
+					WithCachePolicy(PathSubtitles, CachePolicy{MaxAge: 1}),
+				).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+				if got := rr.Header().Get("Cache-Control"); got != tt.want {
+					t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+// errorMetaProvider - mockProvider failing every meta request
+type errorMetaProvider struct {
+	mockProvider
+}
+
+func (p *errorMetaProvider) GetMeta(w http.ResponseWriter, r *http.Request, token string, args *MetaArgs) *Meta {
+	http.Error(w, "Not found", http.StatusNotFound)
+	return nil
+}
@@ -0,0 +1,343 @@
+package stremigo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultClientTimeout - timeout of a single AddonClient request
+const DefaultClientTimeout = 10 * time.Second
+
+// DefaultClientCacheEntries - how many responses AddonClient keeps in its cache
+const DefaultClientCacheEntries = 1000
+
+// maxResponseSize - limit of the response body read by AddonClient, larger responses fail
+const maxResponseSize = 10 << 20
+
+// ClientOption - configures AddonClient created by NewAddonClient
+type ClientOption func(c *AddonClient)
+
+// AddonClient - client of a remote Stremio addon; responses are cached in MemoryStore for CacheMaxAge
+// of the response, or max-age of its Cache-Control header when it sets none
+type AddonClient struct {
+	baseURL    string
+	httpClient *http.Client
+	timeout    time.Duration
+	cache      bool
+	maxEntries int
+	defaultTTL time.Duration
+	now        func() time.Time
+	store      *MemoryStore
+}
+
+// NewAddonClient - creates client of the addon at the transport URL, with or without the manifest.json suffix,
+// e.g. "https://v3-cinemeta.strem.io/manifest.json"
+func NewAddonClient(transportURL string, opts ...ClientOption) (*AddonClient, error) {
+	u, err := url.Parse(transportURL)
+	if err != nil {
+		return nil, fmt.Errorf("stremigo: invalid transport url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("stremigo: invalid transport url %q: http or https scheme required", transportURL)
+	}
+
+	u.RawQuery, u.Fragment = "", ""
+	base := strings.TrimSuffix(strings.TrimSuffix(u.String(), "/"+PathManifest), "/")
+
+	c := &AddonClient{
+		baseURL:    base,
+		httpClient: http.DefaultClient,
+		timeout:    DefaultClientTimeout,
+		cache:      true,
+		maxEntries: DefaultClientCacheEntries,
+		now:        time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.store = NewMemoryStore(c.maxEntries)
+	c.store.now = func() time.Time { return c.now() }
+
+	return c, nil
+}
+
+// WithHTTPClient - sends requests by the client instead of http.DefaultClient
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *AddonClient) {
+		c.httpClient = client
+	}
+}
+
+// WithClientTimeout - limits every request, DefaultClientTimeout by default; 0 disables the limit, so only
+// the context of the call applies
+func WithClientTimeout(timeout time.Duration) ClientOption {
+	return func(c *AddonClient) {
+		c.timeout = timeout
+	}
+}
+
+// WithClientCache - caches responses for their CacheMaxAge, enabled by default
+func WithClientCache(enabled bool) ClientOption {
+	return func(c *AddonClient) {
+		c.cache = enabled
+	}
+}
+
+// WithClientCacheMaxEntries - replaces DefaultClientCacheEntries, the least recently used responses are evicted
+// when the cache is full; zero means no limit
+func WithClientCacheMaxEntries(n int) ClientOption {
+	return func(c *AddonClient) {
+		c.maxEntries = n
+	}
+}
+
+// WithClientDefaultTTL - caches responses setting neither CacheMaxAge nor Cache-Control max-age for the ttl,
+// they are not cached by default
+func WithClientDefaultTTL(ttl time.Duration) ClientOption {
+	return func(c *AddonClient) {
+		c.defaultTTL = ttl
+	}
+}
+
+// TransportURL - returns URL of the remote manifest
+func (c *AddonClient) TransportURL() string {
+	return c.baseURL + "/" + PathManifest
+}
+
+// Manifest - fetches the manifest
+func (c *AddonClient) Manifest(ctx context.Context) (*AddonManifest, error) {
+	manifest := &AddonManifest{}
+	if err := c.get(ctx, "/"+PathManifest, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// AddonCatalog - fetches the addon catalog
+func (c *AddonClient) AddonCatalog(ctx context.Context, args *AddonCatalogArgs) (*AddonCatalogList, error) {
+	list := &AddonCatalogList{}
+	if err := c.get(ctx, resourcePathOf(PathAddonCatalog, args.Type, args.ID, args.Extra), list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Catalog - fetches the catalog
+func (c *AddonClient) Catalog(ctx context.Context, args *CatalogArgs) (*MetaPreviewList, error) {
+	list := &MetaPreviewList{}
+	if err := c.get(ctx, resourcePathOf(PathCatalog, args.Type, args.ID, args.Extra), list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// metaResponse - response of the meta resource, {"meta": {...}}
+type metaResponse struct {
+	Meta        *Meta `json:"meta"`
+	CacheMaxAge int   `json:"cacheMaxAge,omitempty"`
+}
+
+// Meta - fetches the meta; both {"meta": {...}} and the bare meta object are accepted, fails with ErrNotFound
+// for {"meta": null}
+func (c *AddonClient) Meta(ctx context.Context, args *MetaArgs) (*Meta, error) {
+	var raw json.RawMessage
+	if err := c.get(ctx, resourcePathOf(PathMeta, args.Type, args.ID, args.Extra), &raw); err != nil {
+		return nil, err
+	}
+
+	var resp metaResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("%w: invalid meta response: %v", ErrUpstreamUnavailable, err)
+	}
+	if resp.Meta != nil {
+		return resp.Meta, nil
+	}
+
+	meta := &Meta{}
+	if err := json.Unmarshal(raw, meta); err != nil || meta.ID == "" {
+		return nil, fmt.Errorf("%w: meta %s/%s", ErrNotFound, args.Type, args.ID)
+	}
+	return meta, nil
+}
+
+// Stream - fetches streams of the video
+func (c *AddonClient) Stream(ctx context.Context, args *StreamArgs) (*StreamList, error) {
+	list := &StreamList{}
+	if err := c.get(ctx, resourcePathOf(PathStream, args.Type, args.ID, args.Extra), list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Subtitles - fetches subtitles of the video; VideoHash, VideoSize and Filename are sent as extra properties
+func (c *AddonClient) Subtitles(ctx context.Context, args *SubtitlesArgs) (*SubtitlesList, error) {
+	extra := maps.Clone(args.Extra)
+	if extra == nil {
+		extra = ExtraArgs{}
+	}
+	if args.VideoHash != "" {
+		extra.Set(SubtitlesExtraVideoHash, args.VideoHash)
+	}
+	if args.VideoSize > 0 {
+		extra.Set(SubtitlesExtraVideoSize, strconv.FormatInt(args.VideoSize, 10))
+	}
+	if args.Filename != "" {
+		extra.Set(SubtitlesExtraFilename, args.Filename)
+	}
+
+	list := &SubtitlesList{}
+	if err := c.get(ctx, resourcePathOf(PathSubtitles, args.Type, args.ID, extra), list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// resourcePathOf - builds escaped /{resource}/{type}/{id}[/{extra}].json path
+func resourcePathOf(resource, typ, id string, extra ExtraArgs) string {
+	path := "/" + resource + "/" + url.PathEscape(typ) + "/" + url.PathEscape(id)
+	if encoded := extra.Encode(); encoded != "" {
+		path += "/" + encoded
+	}
+	return path + jsonSuffix
+}
+
+// get - fetches the path and decodes the response to v, from the cache when fresh
+func (c *AddonClient) get(ctx context.Context, path string, v any) error {
+	target := c.baseURL + path
+
+	if body, ok := c.cached(ctx, target); ok {
+		return decodeResponse(body, v)
+	}
+
+	body, header, err := c.fetch(ctx, target)
+	if err != nil {
+		return err
+	}
+	if err = decodeResponse(body, v); err != nil {
+		return err
+	}
+
+	ttl, ok := responseTTL(v, header)
+	if !ok {
+		ttl = c.defaultTTL
+	}
+	if c.cache && ttl > 0 {
+		c.store.Set(ctx, target, body, ttl)
+	}
+	return nil
+}
+
+// cached - returns fresh cached body of the URL
+func (c *AddonClient) cached(ctx context.Context, target string) ([]byte, bool) {
+	if !c.cache {
+		return nil, false
+	}
+
+	body, err := c.store.Get(ctx, target)
+	return body, err == nil
+}
+
+// fetch - sends GET request and maps error statuses to the sentinel errors, see StatusCode
+func (c *AddonClient) fetch(ctx context.Context, target string) ([]byte, http.Header, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stremigo: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, fmt.Errorf("stremigo: GET %s: %w", target, ctx.Err())
+		}
+		return nil, nil, fmt.Errorf("%w: GET %s: %v", ErrUpstreamUnavailable, target, err)
+	}
+	defer resp.Body.Close()
+
+	// one byte over the limit is read, so an oversized response fails rather than being truncated
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, fmt.Errorf("stremigo: GET %s: %w", target, ctx.Err())
+		}
+		return nil, nil, fmt.Errorf("%w: GET %s: %v", ErrUpstreamUnavailable, target, err)
+	}
+	if len(body) > maxResponseSize {
+		return nil, nil, fmt.Errorf("%w: GET %s: response exceeds %d bytes", ErrUpstreamUnavailable, target, maxResponseSize)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, nil, fmt.Errorf("%w: GET %s: status %d", statusError(resp.StatusCode), target, resp.StatusCode)
+	}
+
+	return body, resp.Header, nil
+}
+
+// statusError - returns sentinel error of the HTTP status code, the inverse of StatusCode
+func statusError(status int) error {
+	switch {
+	case status == http.StatusBadRequest:
+		return ErrBadRequest
+	case status == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case status == http.StatusForbidden:
+		return ErrForbidden
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return ErrUpstreamUnavailable
+	}
+}
+
+// decodeResponse - decodes JSON body to v
+func decodeResponse(body []byte, v any) error {
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrUpstreamUnavailable, err)
+	}
+	return nil
+}
+
+// responseTTL - returns CacheMaxAge of the response or max-age of Cache-Control header, false when
+// the response sets neither; no-store and no-cache responses are never cached
+func responseTTL(v any, header http.Header) (time.Duration, bool) {
+	control := strings.ToLower(header.Get("Cache-Control"))
+	if strings.Contains(control, "no-store") || strings.Contains(control, "no-cache") {
+		return 0, true
+	}
+
+	if c, ok := v.(cacheable); ok && c.CachePolicy().MaxAge > 0 {
+		return time.Duration(c.CachePolicy().MaxAge) * time.Second, true
+	}
+	if raw, ok := v.(*json.RawMessage); ok {
+		var resp metaResponse
+		if json.Unmarshal(*raw, &resp) == nil && resp.CacheMaxAge > 0 {
+			return time.Duration(resp.CacheMaxAge) * time.Second, true
+		}
+	}
+
+	for _, directive := range strings.Split(control, ",") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(directive), "max-age="); ok {
+			if seconds, err := strconv.Atoi(value); err == nil {
+				return time.Duration(seconds) * time.Second, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,277 @@
+package stremigo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStandInAddon - serves mockProvider under /addon/token/ and counts the requests
+func newStandInAddon(t *testing.T) (*httptest.Server, *mockProvider, *atomic.Int32) {
+	t.Helper()
+
+	p := &mockProvider{secured: true}
+	hits := &atomic.Int32{}
+	counter := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+
+	srv := httptest.NewServer(NewServer(AdaptProvider(p), WithBasePath("/addon"), WithMiddleware(counter)))
+	t.Cleanup(srv.Close)
+	return srv, p, hits
+}
+
+func TestNewAddonClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{name: "manifest url", url: "https://example.com/addon/manifest.json", want: "https://example.com/addon/manifest.json"},
+		{name: "base url", url: "https://example.com/addon/", want: "https://example.com/addon/manifest.json"},
+		{name: "unsupported scheme", url: "stremio://example.com/manifest.json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				c, err := NewAddonClient(tt.url)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("NewAddonClient() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err == nil && c.TransportURL() != tt.want {
+					t.Errorf("TransportURL() = %q, want %q", c.TransportURL(), tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestAddonClient(t *testing.T) {
+	srv, p, _ := newStandInAddon(t)
+	ctx := context.Background()
+
+	c, err := NewAddonClient(srv.URL + "/addon/token/manifest.json")
+	if err != nil {
+		t.Fatalf("NewAddonClient() error = %v", err)
+	}
+
+	manifest, err := c.Manifest(ctx)
+	if err != nil || manifest.ID != "com.example.mock" {
+		t.Errorf("Manifest() = %+v, %v, want the mock manifest", manifest, err)
+	}
+
+	extra := ExtraArgs{CatalogExtraSearched: {"the matrix"}, CatalogExtraSkip: {"100"}}
+	if _, err = c.Catalog(ctx, &CatalogArgs{Type: TypeMovie, ID: "top", Extra: extra}); err != nil {
+		t.Fatalf("Catalog() error = %v", err)
+	}
+	if want := (&CatalogArgs{Type: TypeMovie, ID: "top", Extra: extra}); !reflect.DeepEqual(p.args, want) {
+		t.Errorf("provider args = %+v, want %+v", p.args, want)
+	}
+	if p.token != "token" {
+		t.Errorf("provider token = %q, want %q", p.token, "token")
+	}
+
+	meta, err := c.Meta(ctx, &MetaArgs{Type: TypeSeries, ID: "tt0944947"})
+	if err != nil || meta.ID != "tt0944947" {
+		t.Errorf("Meta() = %+v, %v, want the bare meta of the legacy provider", meta, err)
+	}
+
+	if _, err = c.Stream(ctx, &StreamArgs{Type: TypeSeries, ID: "tt0944947:1:2"}); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if want := (&StreamArgs{Type: TypeSeries, ID: "tt0944947:1:2", Extra: ExtraArgs{}}); !reflect.DeepEqual(p.args, want) {
+		t.Errorf("provider args = %+v, want %+v", p.args, want)
+	}
+
+	subtitles, err := c.Subtitles(ctx, &SubtitlesArgs{Type: TypeMovie, ID: "tt0111161", VideoHash: "abc", VideoSize: 1024})
+	if err != nil || len(subtitles.Subtitles) != 1 {
+		t.Fatalf("Subtitles() = %+v, %v, want one subtitles", subtitles, err)
+	}
+	if args := p.args.(*SubtitlesArgs); args.VideoHash != "abc" || args.VideoSize != 1024 {
+		t.Errorf("provider args = %+v, want video hash and size", args)
+	}
+
+	if _, err = c.AddonCatalog(ctx, &AddonCatalogArgs{Type: TypeMovie, ID: "all"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddonCatalog() error = %v, want ErrNotFound of provider without addon catalogs", err)
+	}
+}
+
+func TestAddonClientCache(t *testing.T) {
+	srv, _, hits := newStandInAddon(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	c, _ := NewAddonClient(srv.URL + "/addon/token/")
+	c.now = func() time.Time { return now }
+
+	args := &SubtitlesArgs{Type: TypeMovie, ID: "tt0111161"}
+	for range 2 {
+		if _, err := c.Subtitles(ctx, args); err != nil {
+			t.Fatalf("Subtitles() error = %v", err)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1 as the response sets cacheMaxAge", got)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := c.Subtitles(ctx, args); err != nil {
+		t.Fatalf("Subtitles() error = %v", err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2 after cacheMaxAge expired", got)
+	}
+
+	for range 2 {
+		if _, err := c.Stream(ctx, &StreamArgs{Type: TypeMovie, ID: "tt0111161"}); err != nil {
+			t.Fatalf("Stream() error = %v", err)
+		}
+	}
+	if got := hits.Load(); got != 4 {
+		t.Errorf("requests = %d, want 4 as streams set no cacheMaxAge", got)
+	}
+}
+
+func TestAddonClientMetaResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantID  string
+		wantErr error
+	}{
+		{name: "wrapped", body: `{"meta": {"id": "tt0111161", "type": "movie", "name": "Shawshank"}}`, wantID: "tt0111161"},
+		{name: "bare", body: `{"id": "tt0111161", "type": "movie", "name": "Shawshank"}`, wantID: "tt0111161"},
+		{name: "null", body: `{"meta": null}`, wantErr: ErrNotFound},
+		{name: "invalid", body: `<html>`, wantErr: ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				srv := httptest.NewServer(
+					http.HandlerFunc(
+						func(w http.ResponseWriter, r *http.Request) {
+							w.Write([]byte(tt.body))
+						},
+					),
+				)
+				defer srv.Close()
+
+				c, _ := NewAddonClient(srv.URL)
+				meta, err := c.Meta(context.Background(), &MetaArgs{Type: TypeMovie, ID: "tt0111161"})
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("Meta() error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil || meta.ID != tt.wantID {
+					t.Errorf("Meta() = %+v, %v, want id %q", meta, err, tt.wantID)
+				}
+			},
+		)
+	}
+}
+
+func TestAddonClientCacheMaxEntries(t *testing.T) {
+	srv, _, hits := newStandInAddon(t)
+	ctx := context.Background()
+
+	c, _ := NewAddonClient(srv.URL+"/addon/token/", WithClientCacheMaxEntries(1))
+
+	for _, id := range []string{"tt0111161", "tt0068646", "tt0111161"} {
+		if _, err := c.Subtitles(ctx, &SubtitlesArgs{Type: TypeMovie, ID: id}); err != nil {
+			t.Fatalf("Subtitles() error = %v", err)
+		}
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3 as the first response was evicted", got)
+	}
+	if got := c.store.Len(); got != 1 {
+		t.Errorf("cached responses = %d, want 1", got)
+	}
+}
+
+func TestAddonClientErrors(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/stream/movie/slow.json":
+					select {
+					case <-r.Context().Done():
+					case <-time.After(time.Second):
+					}
+				case "/stream/movie/missing.json":
+					w.WriteHeader(http.StatusNotFound)
+				case "/stream/movie/private.json":
+					w.WriteHeader(http.StatusUnauthorized)
+				default:
+					w.WriteHeader(http.StatusBadGateway)
+				}
+			},
+		),
+	)
+	defer srv.Close()
+
+	c, _ := NewAddonClient(srv.URL, WithClientTimeout(50*time.Millisecond))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      string
+		wantErr error
+	}{
+		{name: "timeout", ctx: context.Background(), id: "slow", wantErr: context.DeadlineExceeded},
+		{name: "cancelled", ctx: cancelled, id: "slow", wantErr: context.Canceled},
+		{name: "not found", ctx: context.Background(), id: "missing", wantErr: ErrNotFound},
+		{name: "unauthorized", ctx: context.Background(), id: "private", wantErr: ErrUnauthorized},
+		{name: "upstream error", ctx: context.Background(), id: "broken", wantErr: ErrUpstreamUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := c.Stream(tt.ctx, &StreamArgs{Type: TypeMovie, ID: tt.id})
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Stream() error = %v, want %v", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+func TestAddonClientResponseTooLarge(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"streams":[],"padding":"`))
+				w.Write(bytes.Repeat([]byte("x"), maxResponseSize))
+				w.Write([]byte(`"}`))
+			},
+		),
+	)
+	defer srv.Close()
+
+	c, _ := NewAddonClient(srv.URL)
+
+	if _, err := c.Stream(context.Background(), &StreamArgs{Type: TypeMovie, ID: "tt0111161"}); !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Errorf("Stream() error = %v, want ErrUpstreamUnavailable of oversized response", err)
+	}
+}
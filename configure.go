@@ -0,0 +1,284 @@
+package stremigo
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Config - user settings submitted on the configure page, keyed by ConfigField.Key; text, password and
+// select values are strings, number values float64 and checkbox values bool
+type Config map[string]any
+
+// ParseConfigForm - parses and validates submitted form values against AddonManifest.Config;
+// returns ValidationErrors with ConfigField.Key as the field of every problem
+func (m *AddonManifest) ParseConfigForm(form url.Values) (Config, error) {
+	var errs ValidationErrors
+	config := Config{}
+
+	for _, f := range m.Config {
+		if f == nil {
+			continue
+		}
+		value := strings.TrimSpace(form.Get(f.Key))
+
+		switch f.Type {
+		case ConfigTypeCheckbox:
+			checked := value != ""
+			if f.Required && !checked {
+				errs.add(f.Key, "must be checked")
+			}
+			config[f.Key] = checked
+			continue
+		}
+
+		if value == "" {
+			if f.Required {
+				errs.add(f.Key, "is required")
+			}
+			continue
+		}
+
+		switch f.Type {
+		case ConfigTypeNumber:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				errs.add(f.Key, "%q is not a number", value)
+				continue
+			}
+			config[f.Key] = number
+		case ConfigTypeSelect:
+			if !slices.Contains(f.Options, value) {
+				errs.add(f.Key, "%q is not one of the options", value)
+				continue
+			}
+			config[f.Key] = value
+		default:
+			config[f.Key] = value
+		}
+	}
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// DefaultConfig - returns Config with ConfigField.Default values
+func (m *AddonManifest) DefaultConfig() Config {
+	config := Config{}
+	for _, f := range m.Config {
+		switch {
+		case f == nil:
+		case f.Type == ConfigTypeCheckbox:
+			config[f.Key] = f.Default == ConfigCheckboxChecked
+		case f.Default == "":
+		case f.Type == ConfigTypeNumber:
+			if number, err := strconv.ParseFloat(f.Default, 64); err == nil {
+				config[f.Key] = number
+			}
+		default:
+			config[f.Key] = f.Default
+		}
+	}
+	return config
+}
+
+// EncodeConfigToken - encodes the config to URL-safe token, base64url encoded JSON, see JSONTokenCodec
+func EncodeConfigToken(config any) (string, error) {
+	return JSONTokenCodec{}.Encode(config)
+}
+
+// DecodeConfigToken - decodes token created by EncodeConfigToken to v, see JSONTokenCodec
+func DecodeConfigToken(token string, v any) error {
+	return JSONTokenCodec{}.Decode(token, v)
+}
+
+// ConfigurePage - default configure page rendering HTML form from AddonManifest.Config; submitted values are
+// validated by AddonManifest.ParseConfigForm and encoded to the token of the stremio:// install URL.
+// The addon has to be secured to receive the token.
+// Manifest - required - manifest declaring the config
+// Codec - optional - codec of the token, must match WithTokenCodec of the Server; defaults to JSONTokenCodec
+// Template - optional - custom template executed with ConfigurePageData, defaults to DefaultConfigureTemplate
+type ConfigurePage struct {
+	Manifest *AddonManifest
+	Codec    TokenCodec
+	Template *template.Template
+}
+
+// ConfigurePageData - data of the configure page template
+// Manifest - manifest declaring the config
+// Values - current values, defaults or values of the current token or submission
+// Errors - problems of the submission keyed by ConfigField.Key
+// InstallURL - stremio:// URL of the configured manifest, set after a successful submission; typed as trusted,
+// html/template would replace the stremio scheme otherwise
+// ManifestURL - http(s):// URL of the configured manifest, set after a successful submission
+type ConfigurePageData struct {
+	Manifest    *AddonManifest
+	Values      Config
+	Errors      map[string]string
+	InstallURL  template.URL
+	ManifestURL string
+}
+
+// RenderConfigurePage - renders the form on GET and validates submission on POST, see ProviderInterface.RenderConfigurePage
+func (p *ConfigurePage) RenderConfigurePage(w http.ResponseWriter, r *http.Request, token string) {
+	data := &ConfigurePageData{Manifest: p.Manifest, Values: p.Manifest.DefaultConfig(), Errors: map[string]string{}}
+	status := http.StatusOK
+
+	codec := p.Codec
+	if codec == nil {
+		codec = JSONTokenCodec{}
+	}
+
+	if token != "" {
+		current := Config{}
+		if err := codec.Decode(token, &current); err == nil {
+			data.Values = current
+		}
+	}
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			WriteErrorResponse(w, NewErrorResponse(fmt.Errorf("%w: invalid form: %v", ErrBadRequest, err), DefaultErrorMessages))
+			return
+		}
+
+		config, err := p.Manifest.ParseConfigForm(r.PostForm)
+		if verrs, ok := err.(ValidationErrors); ok {
+			for _, e := range verrs {
+				data.Errors[e.Field] = e.Message
+			}
+			data.Values = submittedValues(r.PostForm)
+			status = http.StatusUnprocessableEntity
+		} else if configured, err := codec.Encode(config); err == nil {
+			data.Values = config
+			data.ManifestURL, data.InstallURL = installURLs(r, token, configured)
+		} else {
+			WriteErrorResponse(w, NewErrorResponse(err, DefaultErrorMessages))
+			return
+		}
+	}
+
+	tmpl := p.Template
+	if tmpl == nil {
+		tmpl = DefaultConfigureTemplate
+	}
+
+	// the page is rendered before it is written, so a failing template is answered with an error
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, data); err != nil {
+		WriteErrorResponse(w, NewErrorResponse(err, DefaultErrorMessages))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(page.Bytes())
+}
+
+// submittedValues - returns submitted form values, so the form keeps them when re-rendered with errors
+func submittedValues(form url.Values) Config {
+	config := Config{}
+	for key := range form {
+		config[key] = form.Get(key)
+	}
+	return config
+}
+
+// installURLs - returns http(s) and stremio:// URLs of the manifest configured by the token; the base path
+// and the current token are taken from the original request URI, as Server removes them from the request path
+func installURLs(r *http.Request, current, token string) (manifestURL string, installURL template.URL) {
+	base := r.RequestURI
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		base = u.EscapedPath()
+	}
+	base = strings.TrimSuffix(strings.TrimSuffix(base, "/"), "/"+PathConfigure)
+	if current != "" {
+		base = strings.TrimSuffix(base, "/"+url.PathEscape(current))
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	path := base + "/" + url.PathEscape(token) + "/" + PathManifest
+	return scheme + "://" + r.Host + path, template.URL("stremio://" + r.Host + path)
+}
+
+// DefaultConfigureTemplate - template of ConfigurePage, executed with ConfigurePageData
+var DefaultConfigureTemplate = template.Must(template.New("configure").Funcs(template.FuncMap{"value": configValue}).Parse(defaultConfigureHTML))
+
+// configValue - formats config value for the form
+func configValue(values Config, key string) string {
+	switch v := values[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return ConfigCheckboxChecked
+		}
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+const defaultConfigureHTML = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>{{.Manifest.Name}} - Configure</title>
+	<style>
+		body { font-family: sans-serif; max-width: 32rem; margin: 2rem auto; padding: 0 1rem; }
+		label { display: block; margin-top: 1rem; }
+		input, select { display: block; width: 100%; box-sizing: border-box; padding: .4rem; }
+		input[type=checkbox] { display: inline; width: auto; }
+		.error { color: #b00020; }
+		.install { display: inline-block; margin-top: 1.5rem; padding: .6rem 1.2rem; background: #7b5bf5; color: #fff; text-decoration: none; }
+	</style>
+</head>
+<body>
+	{{if .Manifest.Logo}}<img src="{{.Manifest.Logo}}" alt="" width="96">{{end}}
+	<h1>{{.Manifest.Name}}</h1>
+	<p>{{.Manifest.Description}}</p>
+	{{if .InstallURL}}
+	<a class="install" href="{{.InstallURL}}">Install</a>
+	<p><small>{{.ManifestURL}}</small></p>
+	{{end}}
+	<form method="post">
+		{{range .Manifest.Config}}
+		<label for="{{.Key}}">
+			{{if eq .Type "checkbox"}}
+			<input type="checkbox" id="{{.Key}}" name="{{.Key}}" value="checked"{{if value $.Values .Key}} checked{{end}}>
+			{{or .Title .Key}}
+			{{else}}
+			{{or .Title .Key}}
+			{{if eq .Type "select"}}
+			<select id="{{.Key}}" name="{{.Key}}"{{if .Required}} required{{end}}>
+				{{$current := value $.Values .Key}}
+				{{range .Options}}<option value="{{.}}"{{if eq . $current}} selected{{end}}>{{.}}</option>{{end}}
+			</select>
+			{{else}}
+			<input type="{{.Type}}" id="{{.Key}}" name="{{.Key}}" value="{{value $.Values .Key}}"{{if .Required}} required{{end}}>
+			{{end}}
+			{{end}}
+		</label>
+		{{with index $.Errors .Key}}<div class="error">{{.}}</div>{{end}}
+		{{end}}
+		<button type="submit">Save</button>
+	</form>
+</body>
+</html>
+`
@@ -0,0 +1,291 @@
+package stremigo
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testConfig = []*ConfigField{
+	{Key: "apiKey", Type: ConfigTypePassword, Title: "API key", Required: true},
+	{Key: "quality", Type: ConfigTypeSelect, Options: []string{"720p", "1080p"}, Default: "1080p"},
+	{Key: "limit", Type: ConfigTypeNumber, Default: "20"},
+	{Key: "adult", Type: ConfigTypeCheckbox},
+}
+
+func TestConfigFieldValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		field *ConfigField
+		want  []string
+	}{
+		{name: "text", field: &ConfigField{Key: "name", Type: ConfigTypeText, Default: "foo"}},
+		{name: "checked checkbox", field: &ConfigField{Key: "adult", Type: ConfigTypeCheckbox, Default: ConfigCheckboxChecked}},
+		{name: "missing key and type", field: &ConfigField{}, want: []string{"key", "type"}},
+		{name: "unknown type", field: &ConfigField{Key: "k", Type: "color"}, want: []string{"type"}},
+		{name: "select without options", field: &ConfigField{Key: "k", Type: ConfigTypeSelect}, want: []string{"options"}},
+		{name: "options of text", field: &ConfigField{Key: "k", Type: ConfigTypeText, Options: []string{"a"}}, want: []string{"options"}},
+		{name: "default not in options", field: &ConfigField{Key: "k", Type: ConfigTypeSelect, Options: []string{"a"}, Default: "b"}, want: []string{"default"}},
+		{name: "default not a number", field: &ConfigField{Key: "k", Type: ConfigTypeNumber, Default: "ten"}, want: []string{"default"}},
+		{name: "checkbox default", field: &ConfigField{Key: "k", Type: ConfigTypeCheckbox, Default: "true"}, want: []string{"default"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validationFields(tt.field.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestValidateConfig(t *testing.T) {
+	manifest := validManifest()
+	manifest.Config = []*ConfigField{
+		{Key: "apiKey", Type: ConfigTypeText},
+		{Key: "apiKey", Type: ConfigTypePassword},
+	}
+
+	want := []string{"config[1].key"}
+	if got := validationFields(manifest.Validate()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() fields = %v, want %v", got, want)
+	}
+}
+
+func TestParseConfigForm(t *testing.T) {
+	manifest := &AddonManifest{Config: testConfig}
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		want       Config
+		wantFields []string
+	}{
+		{
+			name: "all fields",
+			form: url.Values{"apiKey": {"secret"}, "quality": {"720p"}, "limit": {"50"}, "adult": {"checked"}},
+			want: Config{"apiKey": "secret", "quality": "720p", "limit": 50.0, "adult": true},
+		},
+		{
+			name: "optional fields missing",
+			form: url.Values{"apiKey": {" secret "}},
+			want: Config{"apiKey": "secret", "adult": false},
+		},
+		{
+			name:       "invalid values",
+			form:       url.Values{"quality": {"4k"}, "limit": {"many"}},
+			wantFields: []string{"apiKey", "quality", "limit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := manifest.ParseConfigForm(tt.form)
+			if fields := validationFields(err); !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Fatalf("ParseConfigForm() error fields = %v, want %v", fields, tt.wantFields)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseConfigForm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	want := Config{"quality": "1080p", "limit": 20.0, "adult": false}
+	if got := (&AddonManifest{Config: testConfig}).DefaultConfig(); !reflect.DeepEqual(got, want) {
+		t.Errorf("DefaultConfig() = %v, want %v", got, want)
+	}
+}
+
+func TestConfigNilField(t *testing.T) {
+	manifest := &AddonManifest{Config: []*ConfigField{nil, {Key: "apiKey", Type: ConfigTypeText, Default: "key"}}}
+
+	got, err := manifest.ParseConfigForm(url.Values{"apiKey": {"secret"}})
+	if err != nil {
+		t.Fatalf("ParseConfigForm() error = %v", err)
+	}
+	if want := (Config{"apiKey": "secret"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseConfigForm() = %v, want %v", got, want)
+	}
+	if got, want := manifest.DefaultConfig(), (Config{"apiKey": "key"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("DefaultConfig() = %v, want %v", got, want)
+	}
+}
+
+func TestConfigToken(t *testing.T) {
+	token, err := EncodeConfigToken(Config{"apiKey": "a/b+c", "limit": 20.0})
+	if err != nil {
+		t.Fatalf("EncodeConfigToken() error = %v", err)
+	}
+	if url.PathEscape(token) != token {
+		t.Errorf("EncodeConfigToken() = %q, want a path-safe token", token)
+	}
+
+	got := Config{}
+	if err = DecodeConfigToken(token, &got); err != nil {
+		t.Fatalf("DecodeConfigToken() error = %v", err)
+	}
+	if want := (Config{"apiKey": "a/b+c", "limit": 20.0}); !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeConfigToken() = %v, want %v", got, want)
+	}
+
+	if err = DecodeConfigToken("not a token", &got); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("DecodeConfigToken() error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func newConfigurableAddon(t *testing.T) *Addon {
+	t.Helper()
+
+	manifest := testManifest
+	manifest.Config = testConfig
+
+	addon, err := NewAddonBuilder(manifest).
+		DefineStreamHandler(
+			[]string{TypeMovie}, nil,
+			func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+				return &StreamList{}, nil
+			},
+		).
+		Secured(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	return addon
+}
+
+func TestAddonBuilderConfig(t *testing.T) {
+	addon := newConfigurableAddon(t)
+
+	manifest, _ := addon.Manifest(context.Background(), "")
+	if hints := manifest.BehaviorHints; hints == nil || !hints.Configurable || !hints.ConfigurationRequired {
+		t.Errorf("BehaviorHints = %+v, want configurable and configuration required", hints)
+	}
+
+	unsecured := testManifest
+	unsecured.Config = testConfig
+	_, err := NewAddonBuilder(unsecured).
+		DefineStreamHandler([]string{TypeMovie}, nil, func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+			return &StreamList{}, nil
+		}).
+		Build()
+	if err == nil {
+		t.Error("Build() of unsecured addon with config succeeded, want error")
+	}
+}
+
+func TestConfigurePage(t *testing.T) {
+	server := NewServer(newConfigurableAddon(t), WithBasePath("/addon"))
+
+	token, _ := JSONTokenCodec{}.Encode(Config{"apiKey": "current", "quality": "720p"})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		form       url.Values
+		wantStatus int
+		wantBody   []string
+	}{
+		{
+			name:       "defaults",
+			method:     http.MethodGet,
+			path:       "/addon/configure",
+			wantStatus: http.StatusOK,
+			wantBody:   []string{`name="apiKey"`, `type="password"`, `value="1080p" selected`, `value="20"`},
+		},
+		{
+			name:       "current token",
+			method:     http.MethodGet,
+			path:       "/addon/" + token + "/configure",
+			wantStatus: http.StatusOK,
+			wantBody:   []string{`value="current"`, `value="720p" selected`},
+		},
+		{
+			name:       "invalid submission",
+			method:     http.MethodPost,
+			path:       "/addon/configure",
+			form:       url.Values{"limit": {"many"}},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   []string{"is required", "is not a number", `value="many"`},
+		},
+		{
+			name:       "valid submission",
+			method:     http.MethodPost,
+			path:       "/addon/" + token + "/configure",
+			form:       url.Values{"apiKey": {"secret"}, "quality": {"720p"}},
+			wantStatus: http.StatusOK,
+			wantBody:   []string{`href="stremio://example.com/addon/`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+				t.Errorf("Content-Type = %q, want text/html", ct)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body doesn't contain %q:\n%s", want, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestConfigurePageInstallURL(t *testing.T) {
+	page := &ConfigurePage{Manifest: &AddonManifest{Config: testConfig}}
+
+	form := url.Values{"apiKey": {"secret"}}
+	r := httptest.NewRequest(http.MethodPost, "https://addon.example.com/old/configure", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	page.RenderConfigurePage(w, r, "old")
+
+	token, _ := JSONTokenCodec{}.Encode(Config{"apiKey": "secret", "adult": false})
+	want := "stremio://addon.example.com/" + token + "/" + PathManifest
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("body doesn't contain %q:\n%s", want, w.Body.String())
+	}
+	if want = "https://addon.example.com/" + token + "/" + PathManifest; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("body doesn't contain %q:\n%s", want, w.Body.String())
+	}
+}
+
+func TestConfigurePageTemplateError(t *testing.T) {
+	page := &ConfigurePage{
+		Manifest: &AddonManifest{Config: testConfig},
+		Template: template.Must(template.New("configure").Parse("<p>{{.Missing}}</p>")),
+	}
+
+	w := httptest.NewRecorder()
+	page.RenderConfigurePage(w, httptest.NewRequest(http.MethodGet, "/configure", nil), "")
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if strings.Contains(w.Body.String(), "<p>") {
+		t.Errorf("body contains partial page:\n%s", w.Body.String())
+	}
+}
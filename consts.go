@@ -11,12 +11,13 @@ const (
 
 // Paths
 const (
-	PathManifest  string = "manifest.json"
-	PathCatalog   string = ResourceCatalog
-	PathMeta      string = ResourceMeta
-	PathStream    string = ResourceStream
-	PathSubtitles string = ResourceSubtitles
-	PathConfigure string = "configure"
+	PathManifest     string = "manifest.json"
+	PathAddonCatalog string = ResourceAddonCatalog
+	PathCatalog      string = ResourceCatalog
+	PathMeta         string = ResourceMeta
+	PathStream       string = ResourceStream
+	PathSubtitles    string = ResourceSubtitles
+	PathConfigure    string = "configure"
 )
 
 // Content types
@@ -32,6 +33,28 @@ const (
 	CatalogExtraSearched string = "search"
 	CatalogExtraGenre    string = "genre"
 	CatalogExtraSkip     string = "skip"
+
+	CatalogExtraLastVideosIds     string = "lastVideosIds"
+	CatalogExtraCalendarVideosIds string = "calendarVideosIds"
+)
+
+// Available subtitles extra fields
+const (
+	SubtitlesExtraVideoHash string = "videoHash"
+	SubtitlesExtraVideoSize string = "videoSize"
+	SubtitlesExtraFilename  string = "filename"
+)
+
+// Available ConfigField.Type options
+const (
+	ConfigTypeText     string = "text"
+	ConfigTypeNumber   string = "number"
+	ConfigTypePassword string = "password"
+	ConfigTypeCheckbox string = "checkbox"
+	ConfigTypeSelect   string = "select"
+
+	// ConfigCheckboxChecked - ConfigField.Default of a checkbox checked by default
+	ConfigCheckboxChecked string = "checked"
 )
 
 // Available MetaLink.Category options
@@ -41,10 +64,22 @@ const (
 	LinkCategoryWriter   string = "writer"
 )
 
-// Prefixes for stream ids - not all
+// Prefixes for stream ids - not all, see RegisterIDNamespace
 const (
 	PrefixImdb    string = "tt"
 	PrefixYoutube string = "yt_id:"
+	PrefixKitsu   string = "kitsu:"
+	PrefixTmdb    string = "tmdb:"
+	PrefixMal     string = "mal:"
+)
+
+// Namespaces of content ids, see ContentID
+const (
+	IDNamespaceImdb    string = "imdb"
+	IDNamespaceYoutube string = "youtube"
+	IDNamespaceKitsu   string = "kitsu"
+	IDNamespaceTmdb    string = "tmdb"
+	IDNamespaceMal     string = "mal"
 )
 
 // Available poster shapes
@@ -0,0 +1,137 @@
+package stremigo
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CORSPolicy - Cross-Origin Resource Sharing of the responses
+// AllowedOrigins - origins allowed to read the responses, e.g. "https://web.stremio.com"; "*" allows any origin
+// AllowedMethods - methods allowed in preflight requests
+// AllowedHeaders - request headers allowed in preflight requests, case-insensitive; "*" allows any header
+// ExposedHeaders - response headers readable by the client, e.g. "ETag"
+// AllowCredentials - allows cookies and authorization headers; the request origin is echoed instead of "*"
+// MaxAge - seconds the preflight response may be cached by the client, omitted when 0
+type CORSPolicy struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+// DefaultCORSPolicy - allows any origin to GET and POST with Content-Type header, as Stremio clients need
+var DefaultCORSPolicy = CORSPolicy{
+	AllowedOrigins: []string{"*"},
+	AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodOptions},
+	AllowedHeaders: []string{"Content-Type"},
+}
+
+// WithCORSPolicy - replaces DefaultCORSPolicy
+func WithCORSPolicy(policy CORSPolicy) Option {
+	return func(s *Server) {
+		s.cors = &policy
+	}
+}
+
+// allowsOrigin - reports whether the origin may read the responses
+func (p *CORSPolicy) allowsOrigin(origin string) bool {
+	return slices.Contains(p.AllowedOrigins, "*") || slices.Contains(p.AllowedOrigins, origin)
+}
+
+// allowsHeaders - reports whether all comma separated request headers are allowed
+func (p *CORSPolicy) allowsHeaders(headers string) bool {
+	if slices.Contains(p.AllowedHeaders, "*") {
+		return true
+	}
+
+	for _, header := range strings.Split(headers, ",") {
+		if header = strings.TrimSpace(header); header == "" {
+			continue
+		}
+		if !slices.ContainsFunc(p.AllowedHeaders, func(allowed string) bool { return strings.EqualFold(allowed, header) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// setOrigin - sets Access-Control-Allow-Origin and credentials headers; returns false when the origin is not allowed
+func (p *CORSPolicy) setOrigin(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	h := w.Header()
+
+	// without Origin the response can't be read cross-origin anyway, announce wildcard policy only
+	if origin == "" {
+		if slices.Contains(p.AllowedOrigins, "*") && !p.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		}
+		return true
+	}
+
+	if !p.allowsOrigin(origin) {
+		return false
+	}
+
+	if slices.Contains(p.AllowedOrigins, "*") && !p.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+	}
+	if p.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// setHeaders - sets CORS headers of an actual (non-preflight) request
+func (p *CORSPolicy) setHeaders(w http.ResponseWriter, r *http.Request) {
+	if !p.setOrigin(w, r) {
+		return
+	}
+
+	h := w.Header()
+	h.Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+	h.Set("Access-Control-Allow-Headers", strings.Join(p.AllowedHeaders, ", "))
+	if len(p.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+	}
+}
+
+// isPreflight - reports whether the request is a CORS preflight request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// preflight - validates origin, method and headers of the preflight request and answers it;
+// rejected preflight is answered with 403 without CORS headers
+func (p *CORSPolicy) preflight(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	headers := r.Header.Get("Access-Control-Request-Headers")
+
+	if !p.allowsOrigin(r.Header.Get("Origin")) || !slices.Contains(p.AllowedMethods, method) || !p.allowsHeaders(headers) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	p.setOrigin(w, r)
+	h.Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+	if slices.Contains(p.AllowedHeaders, "*") && headers != "" {
+		h.Set("Access-Control-Allow-Headers", headers)
+	} else {
+		h.Set("Access-Control-Allow-Headers", strings.Join(p.AllowedHeaders, ", "))
+	}
+	if p.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(p.MaxAge))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package stremigo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerCORS(t *testing.T) {
+	private := CORSPolicy{
+		AllowedOrigins:   []string{"https://web.stremio.com"},
+		AllowedMethods:   []string{http.MethodGet},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"ETag"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		method   string
+		headers  map[string]string
+		wantCode int
+		want     map[string]string
+	}{
+		{
+			name:     "default without origin",
+			method:   http.MethodGet,
+			wantCode: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "GET, POST, OPTIONS",
+				"Access-Control-Allow-Headers": "Content-Type",
+			},
+		},
+		{
+			name:     "default with origin",
+			method:   http.MethodGet,
+			headers:  map[string]string{"Origin": "https://example.com"},
+			wantCode: http.StatusOK,
+			want:     map[string]string{"Access-Control-Allow-Origin": "*", "Vary": ""},
+		},
+		{
+			name:     "default preflight",
+			method:   http.MethodOptions,
+			headers:  map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "GET"},
+			wantCode: http.StatusNoContent,
+			want:     map[string]string{"Access-Control-Allow-Origin": "*", "Access-Control-Max-Age": ""},
+		},
+		{
+			name:     "options without preflight",
+			method:   http.MethodOptions,
+			wantCode: http.StatusNoContent,
+			want:     map[string]string{"Allow": "GET, POST, OPTIONS", "Access-Control-Allow-Origin": "*"},
+		},
+		{
+			name:     "preflight with disallowed method",
+			method:   http.MethodOptions,
+			headers:  map[string]string{"Origin": "https://example.com", "Access-Control-Request-Method": "DELETE"},
+			wantCode: http.StatusForbidden,
+			want:     map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:   "preflight with disallowed header",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "content-type, x-secret",
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:   "private preflight",
+			opts:   []Option{WithCORSPolicy(private)},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://web.stremio.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "authorization",
+			},
+			wantCode: http.StatusNoContent,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://web.stremio.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET",
+				"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			name:     "private preflight from other origin",
+			opts:     []Option{WithCORSPolicy(private)},
+			method:   http.MethodOptions,
+			headers:  map[string]string{"Origin": "https://evil.example.com", "Access-Control-Request-Method": "GET"},
+			wantCode: http.StatusForbidden,
+			want:     map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:     "private request",
+			opts:     []Option{WithCORSPolicy(private)},
+			method:   http.MethodGet,
+			headers:  map[string]string{"Origin": "https://web.stremio.com"},
+			wantCode: http.StatusOK,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://web.stremio.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "ETag",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:     "private request from other origin",
+			opts:     []Option{WithCORSPolicy(private)},
+			method:   http.MethodGet,
+			headers:  map[string]string{"Origin": "https://evil.example.com"},
+			wantCode: http.StatusOK,
+			want:     map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Expose-Headers": ""},
+		},
+		{
+			name:     "single origin shorthand",
+			opts:     []Option{WithCORS("https://web.stremio.com")},
+			method:   http.MethodGet,
+			headers:  map[string]string{"Origin": "https://web.stremio.com"},
+			wantCode: http.StatusOK,
+			want:     map[string]string{"Access-Control-Allow-Origin": "https://web.stremio.com"},
+		},
+		{
+			name:     "disabled",
+			opts:     []Option{WithCORS("")},
+			method:   http.MethodGet,
+			headers:  map[string]string{"Origin": "https://web.stremio.com"},
+			wantCode: http.StatusOK,
+			want:     map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				r := httptest.NewRequest(tt.method, "/"+PathManifest, nil)
+				for key, value := range tt.headers {
+					r.Header.Set(key, value)
+				}
+				rr := httptest.NewRecorder()
+
+				NewServer(&mockContextProvider{}, tt.opts...).ServeHTTP(rr, r)
+
+				if rr.Code != tt.wantCode {
+					t.Errorf("status = %d, want %d", rr.Code, tt.wantCode)
+				}
+				for key, value := range tt.want {
+					if got := rr.Header().Get(key); got != value {
+						t.Errorf("header %q = %q, want %q", key, got, value)
+					}
+				}
+			},
+		)
+	}
+}
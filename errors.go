@@ -0,0 +1,195 @@
+package stremigo
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors returned by ContextProvider, Router maps them to HTTP status codes; wrap them
+// with fmt.Errorf("...: %w", ErrNotFound) to add details
+var (
+	ErrBadRequest          = errors.New("stremigo: bad request")
+	ErrUnauthorized        = errors.New("stremigo: unauthorized")
+	ErrForbidden           = errors.New("stremigo: forbidden")
+	ErrNotFound            = errors.New("stremigo: not found")
+	ErrUpstreamUnavailable = errors.New("stremigo: upstream unavailable")
+)
+
+// errResponseWritten - the response has already been written by the provider itself
+var errResponseWritten = errors.New("stremigo: response already written")
+
+// Machine-readable error codes of ErrorResponse
+const (
+	ErrorCodeBadRequest          string = "bad_request"
+	ErrorCodeUnauthorized        string = "unauthorized"
+	ErrorCodeForbidden           string = "forbidden"
+	ErrorCodeNotFound            string = "not_found"
+	ErrorCodeUpstreamUnavailable string = "upstream_unavailable"
+	ErrorCodeTimeout             string = "timeout"
+	ErrorCodeInvalidManifest     string = "invalid_manifest"
+	ErrorCodeInternal            string = "internal_error"
+)
+
+// ErrorMessages - human readable messages keyed by ErrorCode* constants, one language
+type ErrorMessages map[string]string
+
+// DefaultErrorMessages - English messages used when no localised message is available
+var DefaultErrorMessages = ErrorMessages{
+	ErrorCodeBadRequest:          "The request is invalid.",
+	ErrorCodeUnauthorized:        "The request is not authorized.",
+	ErrorCodeForbidden:           "Access to the resource is not allowed.",
+	ErrorCodeNotFound:            "The requested resource was not found.",
+	ErrorCodeUpstreamUnavailable: "The content source is temporarily unavailable.",
+	ErrorCodeTimeout:             "The content source did not respond in time.",
+	ErrorCodeInvalidManifest:     "The addon manifest is invalid.",
+	ErrorCodeInternal:            "An internal error occurred.",
+}
+
+// StatusCode - returns HTTP status code for an error returned by ContextProvider
+func StatusCode(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUpstreamUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ErrorCode - returns ErrorCode* constant for an error returned by ContextProvider
+func ErrorCode(err error) string {
+	var verrs ValidationErrors
+
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		return ErrorCodeBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return ErrorCodeUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return ErrorCodeForbidden
+	case errors.Is(err, ErrNotFound):
+		return ErrorCodeNotFound
+	case errors.Is(err, ErrUpstreamUnavailable):
+		return ErrorCodeUpstreamUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	case errors.As(err, &verrs):
+		return ErrorCodeInvalidManifest
+	default:
+		return ErrorCodeInternal
+	}
+}
+
+// ErrorResponse - JSON error envelope written by DefaultErrorHandler, e.g.
+// {"error": {"status": 404, "code": "not_found", "message": "The requested resource was not found."}}
+type ErrorResponse struct {
+	Error *ErrorBody `json:"error"`
+}
+
+// ErrorBody - error of ErrorResponse
+// Status - HTTP status code
+// Code - machine-readable ErrorCode* constant
+// Message - human readable, possibly localised message
+// Detail - optional - description of the problem; only for client errors and invalid manifest, internal errors are never exposed
+type ErrorBody struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// NewErrorResponse - creates ErrorResponse of the error with a message from messages,
+// DefaultErrorMessages are used for codes missing in messages
+func NewErrorResponse(err error, messages ErrorMessages) *ErrorResponse {
+	body := &ErrorBody{Status: StatusCode(err), Code: ErrorCode(err)}
+
+	body.Message = messages[body.Code]
+	if body.Message == "" {
+		body.Message = DefaultErrorMessages[body.Code]
+	}
+
+	if body.Status < http.StatusInternalServerError || body.Code == ErrorCodeInvalidManifest {
+		body.Detail = err.Error()
+	}
+
+	return &ErrorResponse{Error: body}
+}
+
+// DefaultErrorHandler - writes ErrorResponse with DefaultErrorMessages
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	WriteErrorResponse(w, NewErrorResponse(err, DefaultErrorMessages))
+}
+
+// LocalizedErrorHandler - returns ErrorHandler writing ErrorResponse with messages in the language preferred
+// by Accept-Language header; languages are keyed by primary language subtag, e.g. "cs" or "de"
+func LocalizedErrorHandler(languages map[string]ErrorMessages) ErrorHandler {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		messages := DefaultErrorMessages
+		for _, lang := range acceptedLanguages(r.Header.Get("Accept-Language")) {
+			if m, ok := languages[lang]; ok {
+				messages = m
+				break
+			}
+		}
+		WriteErrorResponse(w, NewErrorResponse(err, messages))
+	}
+}
+
+// WriteErrorResponse - writes the envelope with its status; use it for errors answered outside of Router,
+// e.g. by RenderConfigurePage, so every error of the addon has the same form
+func WriteErrorResponse(w http.ResponseWriter, resp *ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(resp.Error.Status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// acceptedLanguages - returns lowercase primary subtags of Accept-Language header ordered by quality
+func acceptedLanguages(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []language
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = v
+			}
+		}
+		languages = append(languages, language{tag: tag, quality: quality})
+	}
+
+	slices.SortStableFunc(languages, func(a, b language) int { return cmp.Compare(b.quality, a.quality) })
+
+	tags := make([]string, len(languages))
+	for i, l := range languages {
+		tags[i] = l.tag
+	}
+	return tags
+}
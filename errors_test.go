@@ -0,0 +1,187 @@
+package stremigo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "bad request", err: ErrInvalidPath, want: ErrorCodeBadRequest},
+		{name: "unauthorized", err: ErrUnauthorized, want: ErrorCodeUnauthorized},
+		{name: "forbidden", err: fmt.Errorf("catalog: %w", ErrForbidden), want: ErrorCodeForbidden},
+		{name: "not found", err: fmt.Errorf("meta: %w", ErrNotFound), want: ErrorCodeNotFound},
+		{name: "upstream", err: ErrUpstreamUnavailable, want: ErrorCodeUpstreamUnavailable},
+		{name: "invalid manifest", err: (&AddonManifest{}).Validate(), want: ErrorCodeInvalidManifest},
+		{name: "internal", err: errors.New("boom"), want: ErrorCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := ErrorCode(tt.err); got != tt.want {
+					t.Errorf("ErrorCode(%v) = %q, want %q", tt.err, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestNewErrorResponseHidesInternalErrors(t *testing.T) {
+	got := NewErrorResponse(errors.New("database password is hunter2"), nil)
+
+	want := &ErrorResponse{
+		Error: &ErrorBody{
+			Status:  http.StatusInternalServerError,
+			Code:    ErrorCodeInternal,
+			Message: DefaultErrorMessages[ErrorCodeInternal],
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewErrorResponse() = %+v, want %+v", got.Error, want.Error)
+	}
+}
+
+func TestServerErrorResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider ContextProvider
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "unknown page",
+			provider: &mockContextProvider{},
+			path:     "/unknown",
+			wantCode: http.StatusNotFound,
+			wantBody: ErrorCodeNotFound,
+		},
+		{
+			name:     "missing token",
+			provider: AdaptProvider(&mockProvider{secured: true}),
+			path:     "/" + PathManifest,
+			wantCode: http.StatusBadRequest,
+			wantBody: ErrorCodeBadRequest,
+		},
+		{
+			name:     "invalid path",
+			provider: &mockContextProvider{},
+			path:     "/catalog/movie/top",
+			wantCode: http.StatusBadRequest,
+			wantBody: ErrorCodeBadRequest,
+		},
+		{
+			name:     "provider error",
+			provider: &mockContextProvider{err: ErrUpstreamUnavailable},
+			path:     "/stream/movie/tt0111161.json",
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: ErrorCodeUpstreamUnavailable,
+		},
+		{
+			name:     "error written by legacy provider",
+			provider: AdaptProvider(&writingProvider{}),
+			path:     "/stream/movie/tt0111161.json",
+			wantCode: http.StatusBadGateway,
+			wantBody: "Upstream down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+
+				NewServer(tt.provider).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+				if rr.Code != tt.wantCode {
+					t.Errorf("status = %d, want %d", rr.Code, tt.wantCode)
+				}
+				if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+					t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, "*")
+				}
+				if !strings.Contains(rr.Body.String(), tt.wantBody) {
+					t.Errorf("body = %q, want it to contain %q", rr.Body.String(), tt.wantBody)
+				}
+			},
+		)
+	}
+}
+
+func TestServerErrorEnvelope(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	NewServer(&mockContextProvider{}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/catalog/movie/top", nil))
+
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want %q", got, "application/json")
+	}
+
+	var got ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	want := ErrorBody{
+		Status:  http.StatusBadRequest,
+		Code:    ErrorCodeBadRequest,
+		Message: DefaultErrorMessages[ErrorCodeBadRequest],
+		Detail:  ErrInvalidPath.Error(),
+	}
+	if got.Error == nil || *got.Error != want {
+		t.Errorf("error = %+v, want %+v", got.Error, want)
+	}
+}
+
+func TestLocalizedErrorHandler(t *testing.T) {
+	handler := LocalizedErrorHandler(
+		map[string]ErrorMessages{
+			"cs": {ErrorCodeNotFound: "Požadovaný obsah nebyl nalezen."},
+			"de": {ErrorCodeNotFound: "Der angeforderte Inhalt wurde nicht gefunden."},
+		},
+	)
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		err            error
+		want           string
+	}{
+		{name: "no header", err: ErrNotFound, want: DefaultErrorMessages[ErrorCodeNotFound]},
+		{name: "exact language", acceptLanguage: "cs", err: ErrNotFound, want: "Požadovaný obsah nebyl nalezen."},
+		{name: "region subtag", acceptLanguage: "de-AT", err: ErrNotFound, want: "Der angeforderte Inhalt wurde nicht gefunden."},
+		{name: "by quality", acceptLanguage: "en;q=0.5, cs;q=0.8, de;q=0.9", err: ErrNotFound, want: "Der angeforderte Inhalt wurde nicht gefunden."},
+		{name: "unknown language", acceptLanguage: "fr, *;q=0.1", err: ErrNotFound, want: DefaultErrorMessages[ErrorCodeNotFound]},
+		{name: "missing translation", acceptLanguage: "cs", err: ErrUnauthorized, want: DefaultErrorMessages[ErrorCodeUnauthorized]},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Accept-Language", tt.acceptLanguage)
+
+				handler(rr, r, tt.err)
+
+				var got ErrorResponse
+				if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+					t.Fatalf("decoding response: %v", err)
+				}
+				if got.Error.Message != tt.want {
+					t.Errorf("message = %q, want %q", got.Error.Message, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,152 @@
+package stremigo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type validatorsKey struct{}
+
+// validators - ETag and Last-Modified of the currently served response, set by the provider; the ETag is bound
+// to the hash of the response the provider returned
+type validators struct {
+	mu           sync.Mutex
+	etag         string
+	lastModified time.Time
+	bound        string
+}
+
+func withValidators(ctx context.Context) context.Context {
+	return context.WithValue(ctx, validatorsKey{}, &validators{})
+}
+
+func validatorsFromContext(ctx context.Context) *validators {
+	v, _ := ctx.Value(validatorsKey{}).(*validators)
+	return v
+}
+
+// SetETag - sets ETag of the response to the current request, e.g. the version of the data the provider has read;
+// the hash of the encoded response is used otherwise, or when ResourceMiddleware rewrites the response returned
+// by the provider. Unquoted tags are quoted, weak tags (W/"...") are kept. It has no effect outside of Router.
+func SetETag(ctx context.Context, etag string) {
+	if v := validatorsFromContext(ctx); v != nil {
+		v.mu.Lock()
+		v.etag = etag
+		v.mu.Unlock()
+	}
+}
+
+// SetLastModified - sets Last-Modified of the response to the current request; responses without it,
+// the manifest included, send none. It has no effect outside of Router.
+func SetLastModified(ctx context.Context, t time.Time) {
+	if v := validatorsFromContext(ctx); v != nil {
+		v.mu.Lock()
+		v.lastModified = t
+		v.mu.Unlock()
+	}
+}
+
+// get - returns ETag and Last-Modified set by the provider
+func (v *validators) get() (string, time.Time) {
+	if v == nil {
+		return "", time.Time{}
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.etag, v.lastModified
+}
+
+// bind - binds ETag set by the provider to the response encoded by json.Marshal
+func (v *validators) bind(body []byte) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.bound = computeETag(body)
+}
+
+// etagOf - returns ETag of the response encoded by json.Encoder: the one set by the provider when the response
+// is the one it was bound to, the hash of the response otherwise
+func (v *validators) etagOf(body []byte) string {
+	etag, _ := v.get()
+	if etag == "" {
+		return computeETag(body)
+	}
+
+	v.mu.Lock()
+	bound := v.bound
+	v.mu.Unlock()
+	if bound != computeETag(bytes.TrimSuffix(body, []byte("\n"))) {
+		return computeETag(body)
+	}
+	return quoteETag(etag)
+}
+
+// bindValidators - ResourceMiddleware binding ETag set by the provider to its response, so a response rewritten
+// by the outer middleware gets the hash of its own; it wraps the provider call directly
+func bindValidators(next ResourceHandler) ResourceHandler {
+	return func(ctx context.Context, req *ResourceRequest) (any, error) {
+		data, err := next(ctx, req)
+		v := validatorsFromContext(ctx)
+		if etag, _ := v.get(); etag == "" || err != nil || data == nil {
+			return data, err
+		}
+
+		body, merr := json.Marshal(data)
+		if merr != nil {
+			return data, err
+		}
+		v.bind(body)
+		return data, err
+	}
+}
+
+// computeETag - returns strong ETag of the encoded response
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// quoteETag - returns the tag quoted unless it already is
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// notModified - reports whether the conditional GET or HEAD request may be answered with 304 Not Modified;
+// If-None-Match takes precedence over If-Modified-Since, see RFC 9110 section 13.2.2
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			if tag = strings.TrimSpace(tag); tag == "*" || weakETag(tag) == weakETag(etag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// weakETag - returns the opaque tag without the weakness indicator, If-None-Match uses weak comparison
+func weakETag(etag string) string {
+	return strings.TrimPrefix(etag, "W/")
+}
@@ -0,0 +1,193 @@
+package stremigo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newETagServer - serves catalog with ETag computed by Router and streams with ETag and Last-Modified
+// set by the provider, counting the provider calls
+func newETagServer(t *testing.T, opts ...Option) (*Server, *atomic.Int32) {
+	t.Helper()
+
+	calls := &atomic.Int32{}
+	addon, err := NewAddonBuilder(testManifest).
+		DefineCatalogHandler(
+			&Catalog{ID: "top", Type: TypeMovie, Name: "Top"},
+			func(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+				calls.Add(1)
+				return &MetaPreviewList{Metas: []*MetaPreview{{ID: "tt0111161", Name: "Shawshank"}}, CacheMaxAge: 60}, nil
+			},
+		).
+		DefineStreamHandler(
+			[]string{TypeMovie}, nil,
+			func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+				calls.Add(1)
+				SetETag(ctx, "v42")
+				SetLastModified(ctx, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+				return &StreamList{Streams: []*Stream{{URL: "https://example.com/a.mp4"}}, CacheMaxAge: 60}, nil
+			},
+		).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	return NewServer(addon, opts...), calls
+}
+
+func TestServerETag(t *testing.T) {
+	s, calls := newETagServer(t)
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, r)
+		return rr
+	}
+
+	first := get("/catalog/movie/top.json", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("response = %d with ETag %q, want 200 with ETag", first.Code, etag)
+	}
+	if got := get("/catalog/movie/top.json", nil).Header().Get("ETag"); got != etag {
+		t.Errorf("ETag = %q, want %q stable for the same response", got, etag)
+	}
+
+	rr := get("/catalog/movie/top.json", http.Header{"If-None-Match": {`"other", ` + etag}})
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Errorf("conditional response = %d with %d bytes, want 304 without body", rr.Code, rr.Body.Len())
+	}
+	if rr.Header().Get("ETag") != etag || rr.Header().Get("Cache-Control") != "max-age=60, public" {
+		t.Errorf("304 headers = %v, want ETag and Cache-Control", rr.Header())
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("provider calls = %d, want 3, one per request", got)
+	}
+
+	rr = get("/stream/movie/tt0111161.json", http.Header{"If-None-Match": {`W/"v42"`}})
+	if rr.Code != http.StatusNotModified || rr.Header().Get("ETag") != `"v42"` {
+		t.Errorf("response = %d with ETag %q, want 304 with the provider ETag", rr.Code, rr.Header().Get("ETag"))
+	}
+	if got := rr.Header().Get("Last-Modified"); got != "Wed, 01 May 2024 12:00:00 GMT" {
+		t.Errorf("Last-Modified = %q, want the provider one", got)
+	}
+
+	manifest := get("/manifest.json", nil)
+	if got := manifest.Header().Get("Last-Modified"); got != "" {
+		t.Errorf("manifest Last-Modified = %q, want none as the provider sets none", got)
+	}
+	since := time.Now().UTC().Format(http.TimeFormat)
+	if rr = get("/manifest.json", http.Header{"If-Modified-Since": {since}}); rr.Code != http.StatusOK {
+		t.Errorf("manifest status = %d, want 200 as the manifest may have changed", rr.Code)
+	}
+}
+
+func TestServerETagResponseCache(t *testing.T) {
+	s, calls := newETagServer(t, WithResponseCache(NewResponseCache()))
+
+	for range 2 {
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stream/movie/tt0111161.json", nil))
+		if rr.Header().Get("ETag") != `"v42"` || rr.Header().Get("Last-Modified") == "" {
+			t.Errorf("headers = %v, want ETag and Last-Modified of the provider", rr.Header())
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("provider calls = %d, want 1 as the response is cached", got)
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		want   bool
+	}{
+		{name: "unconditional", header: http.Header{}, want: false},
+		{name: "matching tag", header: http.Header{"If-None-Match": {`"abc"`}}, want: true},
+		{name: "weak tag", header: http.Header{"If-None-Match": {`W/"abc"`}}, want: true},
+		{name: "tag in list", header: http.Header{"If-None-Match": {`"x", "abc"`}}, want: true},
+		{name: "any tag", header: http.Header{"If-None-Match": {"*"}}, want: true},
+		{name: "other tag", header: http.Header{"If-None-Match": {`"x"`}}, want: false},
+		{name: "not modified since", header: http.Header{"If-Modified-Since": {"Wed, 01 May 2024 12:00:00 GMT"}}, want: true},
+		{name: "modified since", header: http.Header{"If-Modified-Since": {"Wed, 01 May 2024 11:59:59 GMT"}}, want: false},
+		{
+			name:   "tag takes precedence",
+			header: http.Header{"If-None-Match": {`"x"`}, "If-Modified-Since": {"Wed, 01 May 2024 12:00:00 GMT"}},
+			want:   false,
+		},
+		{name: "post", method: http.MethodPost, header: http.Header{"If-None-Match": {`"abc"`}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				r := httptest.NewRequest(tt.method, "/manifest.json", nil)
+				r.Header = tt.header
+				if got := notModified(r, `"abc"`, modified); got != tt.want {
+					t.Errorf("notModified() = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestServerETagRewritten(t *testing.T) {
+	rewrite := func(next ResourceHandler) ResourceHandler {
+		return func(ctx context.Context, req *ResourceRequest) (any, error) {
+			data, err := next(ctx, req)
+			if list, ok := data.(*StreamList); ok {
+				list.Streams = append(list.Streams, &Stream{URL: "https://example.com/b.mp4"})
+			}
+			return data, err
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "provider", opts: []Option{WithResourceMiddleware(rewrite)}},
+		{name: "response cache", opts: []Option{WithResourceMiddleware(rewrite), WithResponseCache(NewResponseCache())}},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				s, _ := newETagServer(t, tt.opts...)
+
+				for range 2 {
+					r := httptest.NewRequest(http.MethodGet, "/stream/movie/tt0111161.json", nil)
+					r.Header.Set("If-None-Match", `"v42"`)
+					rr := httptest.NewRecorder()
+					s.ServeHTTP(rr, r)
+
+					if rr.Code != http.StatusOK || rr.Header().Get("ETag") == `"v42"` {
+						t.Errorf("response = %d with ETag %q, want 200 with ETag of the rewritten response", rr.Code, rr.Header().Get("ETag"))
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestContextRouterLastModified(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	ContextRouter(rr, httptest.NewRequest(http.MethodGet, "/manifest.json", nil), &mockContextProvider{})
+
+	if got := rr.Header().Get("Last-Modified"); rr.Code != http.StatusOK || got != "" {
+		t.Errorf("response = %d with Last-Modified %q, want 200 without Last-Modified of a per-request server", rr.Code, got)
+	}
+}
@@ -0,0 +1,203 @@
+package stremigo
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ExtraArgs - extra properties of a resource request, encoded in the path as e.g. genre=Action&skip=100;
+// a property may have more values, e.g. genre=Action&genre=Drama when CatalogExtra.OptionsLimit allows it
+type ExtraArgs map[string][]string
+
+// ParseExtraArgs - parses URL-escaped extra segment of the path without the .json suffix
+func ParseExtraArgs(s string) (ExtraArgs, error) {
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+	}
+	return ExtraArgs(values), nil
+}
+
+// Get - returns the first value of the property or an empty string
+func (e ExtraArgs) Get(name string) string {
+	if values := e[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Values - returns all values of the property
+func (e ExtraArgs) Values(name string) []string {
+	return e[name]
+}
+
+// Has - reports whether the property is present
+func (e ExtraArgs) Has(name string) bool {
+	_, ok := e[name]
+	return ok
+}
+
+// Set - replaces values of the property with the value
+func (e ExtraArgs) Set(name, value string) {
+	e[name] = []string{value}
+}
+
+// Add - appends the value to the values of the property
+func (e ExtraArgs) Add(name, value string) {
+	e[name] = append(e[name], value)
+}
+
+// Del - removes the property
+func (e ExtraArgs) Del(name string) {
+	delete(e, name)
+}
+
+// Encode - encodes the properties sorted by name in the form used in the path, e.g. genre=Action&search=foo%20bar;
+// returns an empty string when there are no properties
+func (e ExtraArgs) Encode() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		for _, value := range e[name] {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(escapeExtra(name))
+			sb.WriteByte('=')
+			sb.WriteString(escapeExtra(value))
+		}
+	}
+	return sb.String()
+}
+
+// escapeExtra - escapes like encodeURIComponent, spaces are encoded as %20 instead of +
+func escapeExtra(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// Search - returns CatalogExtraSearched value
+func (e ExtraArgs) Search() string {
+	return e.Get(CatalogExtraSearched)
+}
+
+// Genre - returns CatalogExtraGenre value
+func (e ExtraArgs) Genre() string {
+	return e.Get(CatalogExtraGenre)
+}
+
+// Skip - returns CatalogExtraSkip value, 0 when not present; fails with ErrBadRequest for non-numeric
+// or negative value
+func (e ExtraArgs) Skip() (int, error) {
+	value := e.Get(CatalogExtraSkip)
+	if value == "" {
+		return 0, nil
+	}
+
+	skip, err := strconv.Atoi(value)
+	if err != nil || skip < 0 {
+		return 0, fmt.Errorf("%w: invalid %s %q", ErrBadRequest, CatalogExtraSkip, value)
+	}
+	return skip, nil
+}
+
+// LastVideosIds - returns comma separated CatalogExtraLastVideosIds values
+func (e ExtraArgs) LastVideosIds() []string {
+	return e.list(CatalogExtraLastVideosIds)
+}
+
+// CalendarVideosIds - returns comma separated CatalogExtraCalendarVideosIds values
+func (e ExtraArgs) CalendarVideosIds() []string {
+	return e.list(CatalogExtraCalendarVideosIds)
+}
+
+// SetList - sets the property to comma separated ids, see LastVideosIds and CalendarVideosIds
+func (e ExtraArgs) SetList(name string, ids []string) {
+	e.Set(name, strings.Join(ids, ","))
+}
+
+// list - splits comma separated values of the property
+func (e ExtraArgs) list(name string) []string {
+	var ids []string
+	for _, value := range e[name] {
+		for _, id := range strings.Split(value, ",") {
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// Validate - checks the properties against CatalogExtra declared by the catalog: unknown properties,
+// missing required properties, values outside of CatalogExtra.Options and more values than
+// CatalogExtra.OptionsLimit; a nil catalog declares no extras. Fails with ErrBadRequest listing all problems
+func (e ExtraArgs) Validate(catalog *Catalog) error {
+	var problems []string
+
+	var extras []*CatalogExtra
+	if catalog != nil {
+		extras = catalog.Extra
+	}
+
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	declared := func(name string) bool {
+		return slices.ContainsFunc(extras, func(extra *CatalogExtra) bool { return extra != nil && extra.Name == name })
+	}
+
+	for _, name := range names {
+		if !declared(name) {
+			problems = append(problems, fmt.Sprintf("unknown extra %q", name))
+		}
+	}
+
+	for _, extra := range extras {
+		if extra == nil {
+			continue
+		}
+
+		values, ok := e[extra.Name]
+		if !ok {
+			if extra.IsRequired {
+				problems = append(problems, fmt.Sprintf("extra %q is required", extra.Name))
+			}
+			continue
+		}
+
+		limit := extra.OptionsLimit
+		if limit == 0 {
+			limit = 1
+		}
+		if len(values) > limit {
+			problems = append(problems, fmt.Sprintf("extra %q allows at most %d values", extra.Name, limit))
+		}
+
+		for _, value := range values {
+			if len(extra.Options) > 0 && !slices.Contains(extra.Options, value) {
+				problems = append(problems, fmt.Sprintf("extra %q has invalid option %q", extra.Name, value))
+			}
+		}
+	}
+
+	// undeclared skip is already reported as unknown
+	if _, err := e.Skip(); err != nil && declared(CatalogExtraSkip) {
+		problems = append(problems, fmt.Sprintf("invalid %s %q", CatalogExtraSkip, e.Get(CatalogExtraSkip)))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrBadRequest, strings.Join(problems, "; "))
+	}
+	return nil
+}
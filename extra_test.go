@@ -0,0 +1,212 @@
+package stremigo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseExtraArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    ExtraArgs
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			s:    "",
+			want: ExtraArgs{},
+		},
+		{
+			name: "catalog extras",
+			s:    "genre=Action&skip=100&search=foo%20bar",
+			want: ExtraArgs{CatalogExtraGenre: {"Action"}, CatalogExtraSkip: {"100"}, CatalogExtraSearched: {"foo bar"}},
+		},
+		{
+			name: "repeated values",
+			s:    "genre=Action&genre=Drama",
+			want: ExtraArgs{CatalogExtraGenre: {"Action", "Drama"}},
+		},
+		{
+			name: "plus as space",
+			s:    "search=foo+bar",
+			want: ExtraArgs{CatalogExtraSearched: {"foo bar"}},
+		},
+		{
+			name:    "invalid escaping",
+			s:       "search=%zz",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := ParseExtraArgs(tt.s)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("ParseExtraArgs(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+				}
+				if err != nil {
+					if !errors.Is(err, ErrBadRequest) {
+						t.Errorf("ParseExtraArgs(%q) error = %v, want ErrBadRequest", tt.s, err)
+					}
+					return
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ParseExtraArgs(%q) = %v, want %v", tt.s, got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestExtraArgsEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra ExtraArgs
+		want  string
+	}{
+		{
+			name:  "empty",
+			extra: ExtraArgs{},
+			want:  "",
+		},
+		{
+			name:  "sorted with escaping",
+			extra: ExtraArgs{CatalogExtraSkip: {"100"}, CatalogExtraSearched: {"foo bar&baz"}, CatalogExtraGenre: {"Sci-Fi"}},
+			want:  "genre=Sci-Fi&search=foo%20bar%26baz&skip=100",
+		},
+		{
+			name:  "repeated values",
+			extra: ExtraArgs{CatalogExtraGenre: {"Action", "Drama"}},
+			want:  "genre=Action&genre=Drama",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got := tt.extra.Encode()
+				if got != tt.want {
+					t.Fatalf("Encode() = %q, want %q", got, tt.want)
+				}
+
+				parsed, err := ParseExtraArgs(got)
+				if err != nil {
+					t.Fatalf("ParseExtraArgs(%q) error = %v", got, err)
+				}
+				if !reflect.DeepEqual(parsed, tt.extra) {
+					t.Errorf("ParseExtraArgs(Encode()) = %v, want %v", parsed, tt.extra)
+				}
+			},
+		)
+	}
+}
+
+func TestExtraArgsAccessors(t *testing.T) {
+	extra := ExtraArgs{}
+	extra.Set(CatalogExtraSearched, "foo")
+	extra.Add(CatalogExtraGenre, "Action")
+	extra.Add(CatalogExtraGenre, "Drama")
+	extra.Set(CatalogExtraSkip, "20")
+	extra.SetList(CatalogExtraLastVideosIds, []string{"tt1:1:1", "tt2:1:1"})
+	extra.Add(CatalogExtraCalendarVideosIds, "tt3,tt4")
+	extra.Add(CatalogExtraCalendarVideosIds, "tt5")
+
+	if got := extra.Search(); got != "foo" {
+		t.Errorf("Search() = %q, want %q", got, "foo")
+	}
+	if got := extra.Genre(); got != "Action" {
+		t.Errorf("Genre() = %q, want %q", got, "Action")
+	}
+	if got := extra.Values(CatalogExtraGenre); !reflect.DeepEqual(got, []string{"Action", "Drama"}) {
+		t.Errorf("Values(genre) = %v", got)
+	}
+	if got, err := extra.Skip(); got != 20 || err != nil {
+		t.Errorf("Skip() = %d, %v, want 20, nil", got, err)
+	}
+	if got := extra.LastVideosIds(); !reflect.DeepEqual(got, []string{"tt1:1:1", "tt2:1:1"}) {
+		t.Errorf("LastVideosIds() = %v", got)
+	}
+	if got := extra.CalendarVideosIds(); !reflect.DeepEqual(got, []string{"tt3", "tt4", "tt5"}) {
+		t.Errorf("CalendarVideosIds() = %v", got)
+	}
+
+	extra.Del(CatalogExtraSkip)
+	if extra.Has(CatalogExtraSkip) {
+		t.Error("Has(skip) = true after Del")
+	}
+
+	extra.Set(CatalogExtraSkip, "-1")
+	if _, err := extra.Skip(); !errors.Is(err, ErrBadRequest) {
+		t.Errorf("Skip() error = %v, want ErrBadRequest", err)
+	}
+}
+
+func TestExtraArgsValidate(t *testing.T) {
+	catalog := &Catalog{
+		ID:   "top",
+		Type: TypeMovie,
+		Name: "Top",
+		Extra: []*CatalogExtra{
+			{Name: CatalogExtraGenre, Options: []string{"Action", "Comedy", "Drama"}, OptionsLimit: 2},
+			{Name: CatalogExtraSearched, IsRequired: true},
+			{Name: CatalogExtraSkip},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		extra   ExtraArgs
+		wantErr bool
+	}{
+		{name: "valid", extra: ExtraArgs{CatalogExtraSearched: {"foo"}, CatalogExtraGenre: {"Action", "Drama"}}},
+		{name: "missing required", extra: ExtraArgs{CatalogExtraGenre: {"Action"}}, wantErr: true},
+		{name: "unknown extra", extra: ExtraArgs{CatalogExtraSearched: {"foo"}, "year": {"2020"}}, wantErr: true},
+		{name: "invalid option", extra: ExtraArgs{CatalogExtraSearched: {"foo"}, CatalogExtraGenre: {"Horror"}}, wantErr: true},
+		{
+			name:    "over options limit",
+			extra:   ExtraArgs{CatalogExtraSearched: {"foo"}, CatalogExtraGenre: {"Action", "Comedy", "Drama"}},
+			wantErr: true,
+		},
+		{name: "default limit", extra: ExtraArgs{CatalogExtraSearched: {"foo", "bar"}}, wantErr: true},
+		{name: "invalid skip", extra: ExtraArgs{CatalogExtraSearched: {"foo"}, CatalogExtraSkip: {"ten"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				err := tt.extra.Validate(catalog)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil && !errors.Is(err, ErrBadRequest) {
+					t.Errorf("Validate() error = %v, want ErrBadRequest", err)
+				}
+			},
+		)
+	}
+}
+
+func TestExtraArgsValidateUndeclared(t *testing.T) {
+	catalog := &Catalog{ID: "top", Type: TypeMovie, Name: "Top", Extra: []*CatalogExtra{nil, {Name: CatalogExtraGenre}}}
+
+	err := ExtraArgs{CatalogExtraSkip: {"ten"}}.Validate(catalog)
+	if !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("Validate() error = %v, want ErrBadRequest", err)
+	}
+	if got := strings.Count(err.Error(), CatalogExtraSkip); got != 1 {
+		t.Errorf("Validate() error = %v, want the undeclared skip reported once", err)
+	}
+}
+
+func TestExtraArgsValidateNilCatalog(t *testing.T) {
+	if err := (ExtraArgs{}).Validate(nil); err != nil {
+		t.Errorf("Validate(nil) of no extras error = %v, want nil", err)
+	}
+	if err := (ExtraArgs{CatalogExtraSkip: {"10"}}).Validate(nil); !errors.Is(err, ErrBadRequest) {
+		t.Errorf("Validate(nil) error = %v, want ErrBadRequest of the undeclared extra", err)
+	}
+}
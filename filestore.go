@@ -0,0 +1,277 @@
+package stremigo
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileStoreExt - extension of FileStore value files, other files in the directory are left alone
+const fileStoreExt = ".cache"
+
+// fileStoreTempPrefix, fileStoreTempExt - name of FileStore files being written, removed when the store is
+// created; other temporary files in the directory are left alone
+const (
+	fileStoreTempPrefix = "stremigo-"
+	fileStoreTempExt    = ".tmp"
+)
+
+// FileStore - CacheStore keeping every value in its own file of the directory, so the cache survives restarts.
+// The least recently used files are evicted when their total size exceeds the limit; the order is kept in
+// modification times of the files, so it survives restarts as well. The index of the files is kept in memory,
+// so the directory must be used by a single process only; use RedisStore to share the cache by replicas.
+type FileStore struct {
+	dir      string
+	maxBytes int64
+	now      func() time.Time
+
+	mu    sync.Mutex
+	files map[string]*list.Element
+	lru   *list.List
+	size  int64
+}
+
+// storedFile - value file of FileStore
+type storedFile struct {
+	name string
+	size int64
+}
+
+// NewFileStore - creates FileStore in the directory, which is created when missing, limited to maxBytes
+// of the files, zero means no limit; files of a previous run are kept within the limit and temporary files
+// it left unfinished are removed
+func NewFileStore(dir string, maxBytes int64) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []fs.FileInfo
+	for _, de := range dirEntries {
+		if de.Type().IsRegular() && isFileStoreTemp(de.Name()) {
+			os.Remove(filepath.Join(dir, de.Name()))
+			continue
+		}
+		if !de.Type().IsRegular() || !strings.HasSuffix(de.Name(), fileStoreExt) {
+			continue
+		}
+		if info, err := de.Info(); err == nil {
+			infos = append(infos, info)
+		}
+	}
+	slices.SortFunc(infos, func(a, b fs.FileInfo) int { return a.ModTime().Compare(b.ModTime()) })
+
+	s := &FileStore{dir: dir, maxBytes: maxBytes, now: time.Now, files: map[string]*list.Element{}, lru: list.New()}
+	for _, info := range infos {
+		s.files[info.Name()] = s.lru.PushFront(&storedFile{name: info.Name(), size: info.Size()})
+		s.size += info.Size()
+	}
+	s.evict()
+
+	return s, nil
+}
+
+// Len - returns the number of stored files
+func (s *FileStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// Size - returns the total size of stored files in bytes
+func (s *FileStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Get - returns the value of the key and marks its file as recently used; the file is read without the lock,
+// files are replaced by rename, so it is never read partially
+func (s *FileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	name := fileStoreName(key)
+	path := filepath.Join(s.dir, name)
+
+	data, info, err := readFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.mu.Lock()
+		// Set may have written the file since, its index entry is kept then
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			s.forget(name)
+		}
+		s.mu.Unlock()
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the file starts with the expiry in Unix nanoseconds, zero means no expiry
+	now := s.now()
+	if len(data) < 8 || expiredAt(int64(binary.BigEndian.Uint64(data)), now) {
+		s.mu.Lock()
+		// Set may have replaced the file by a fresh value since it was read, which is kept then
+		if current, err := os.Stat(path); err == nil && os.SameFile(info, current) {
+			s.remove(name)
+		}
+		s.mu.Unlock()
+		return nil, ErrCacheMiss
+	}
+
+	os.Chtimes(path, now, now)
+
+	s.mu.Lock()
+	s.touch(name)
+	s.mu.Unlock()
+
+	return data[8:], nil
+}
+
+// readFile - reads the file together with its info, which identifies the file read when it is replaced later
+func readFile(path string) ([]byte, fs.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, info, nil
+}
+
+// expiredAt - reports whether the expiry in Unix nanoseconds has passed, zero means no expiry
+func expiredAt(expires int64, now time.Time) bool {
+	return expires != 0 && now.UnixNano() >= expires
+}
+
+// Set - writes the value to a temporary file renamed to the file of the key, so readers never see a partial
+// value; values larger than the limit are not stored
+func (s *FileStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	size := int64(8 + len(value))
+	if s.maxBytes > 0 && size > s.maxBytes {
+		return nil
+	}
+
+	data := make([]byte, 8, size)
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(data, uint64(s.now().Add(ttl).UnixNano()))
+	}
+	data = append(data, value...)
+
+	f, err := os.CreateTemp(s.dir, fileStoreTempPrefix+"*"+fileStoreTempExt)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	name := fileStoreName(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = os.Rename(f.Name(), filepath.Join(s.dir, name)); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	now := s.now()
+	os.Chtimes(filepath.Join(s.dir, name), now, now)
+
+	s.track(name, size)
+	s.evict()
+	return nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(filepath.Join(s.dir, fileStoreName(key)))
+	s.forget(fileStoreName(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// track - marks the file as the most recently used one, the caller holds the lock
+func (s *FileStore) track(name string, size int64) {
+	if el, ok := s.files[name]; ok {
+		f := el.Value.(*storedFile)
+		s.size += size - f.size
+		f.size = size
+		s.lru.MoveToFront(el)
+		return
+	}
+
+	s.files[name] = s.lru.PushFront(&storedFile{name: name, size: size})
+	s.size += size
+}
+
+// touch - marks the indexed file as the most recently used one; files removed while they were read are not
+// indexed again, the caller holds the lock
+func (s *FileStore) touch(name string) {
+	if el, ok := s.files[name]; ok {
+		s.lru.MoveToFront(el)
+	}
+}
+
+// forget - drops the file from the index, the caller holds the lock
+func (s *FileStore) forget(name string) {
+	if el, ok := s.files[name]; ok {
+		s.lru.Remove(el)
+		delete(s.files, name)
+		s.size -= el.Value.(*storedFile).size
+	}
+}
+
+// remove - deletes the file and drops it from the index, the caller holds the lock
+func (s *FileStore) remove(name string) {
+	os.Remove(filepath.Join(s.dir, name))
+	s.forget(name)
+}
+
+// evict - removes the least recently used files until their size fits the limit, the caller holds the lock
+func (s *FileStore) evict() {
+	for s.maxBytes > 0 && s.size > s.maxBytes && s.lru.Len() > 0 {
+		s.remove(s.lru.Back().Value.(*storedFile).name)
+	}
+}
+
+// isFileStoreTemp - reports whether the file name is one of the temporary files written by FileStore
+func isFileStoreTemp(name string) bool {
+	return strings.HasPrefix(name, fileStoreTempPrefix) && strings.HasSuffix(name, fileStoreTempExt)
+}
+
+// fileStoreName - returns the file name of the key; keys are hashed, as they may contain any characters
+func fileStoreName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + fileStoreExt
+}
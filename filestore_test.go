@@ -0,0 +1,149 @@
+package stremigo
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	testCacheStore(t, store)
+}
+
+func TestFileStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	clock := &testClock{now: time.Now()}
+
+	// every value takes 8 bytes of expiry and 10 bytes of data
+	store, err := NewFileStore(dir, 40)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	store.now = clock.Now
+
+	for _, key := range []string{"a", "b"} {
+		clock.Add(time.Second)
+		store.Set(ctx, key, []byte("0123456789"), 0)
+	}
+	clock.Add(time.Second)
+	store.Get(ctx, "a")
+	clock.Add(time.Second)
+	store.Set(ctx, "c", []byte("0123456789"), time.Minute)
+
+	if _, err = store.Get(ctx, "b"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() of least recently used key error = %v, want ErrCacheMiss", err)
+	}
+	if store.Len() != 2 || store.Size() != 36 {
+		t.Errorf("Len(), Size() = %d, %d, want 2, 36", store.Len(), store.Size())
+	}
+	if err = store.Set(ctx, "large", make([]byte, 64), 0); err != nil || store.Len() != 2 {
+		t.Errorf("Set() of value over the limit = %v with %d files, want it skipped", err, store.Len())
+	}
+
+	// the order of use is kept in modification times, "a" is older than "c" after restart
+	reopened, err := NewFileStore(dir, 20)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	reopened.now = clock.Now
+	if _, err = reopened.Get(ctx, "a"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() of evicted key after restart error = %v, want ErrCacheMiss", err)
+	}
+	if got, err := reopened.Get(ctx, "c"); err != nil || string(got) != "0123456789" {
+		t.Errorf("Get() after restart = %q, %v, want the stored value", got, err)
+	}
+
+	clock.Add(time.Minute)
+	if _, err = reopened.Get(ctx, "c"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() of expired key error = %v, want ErrCacheMiss", err)
+	}
+	if reopened.Len() != 0 {
+		t.Errorf("Len() = %d, want 0 after the expired file is removed", reopened.Len())
+	}
+}
+
+func TestFileStoreReplacedWhileRead(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{now: time.Now()}
+
+	store, err := NewFileStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	store.now = clock.Now
+	store.Set(ctx, "a", []byte("stale"), time.Minute)
+	clock.Add(2 * time.Minute)
+
+	// the clock is read by Get after the expired file, a fresh value is set at that moment
+	replace := true
+	store.now = func() time.Time {
+		if replace {
+			replace = false
+			store.Set(ctx, "a", []byte("fresh"), 0)
+		}
+		return clock.Now()
+	}
+
+	if _, err = store.Get(ctx, "a"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() of expired value error = %v, want ErrCacheMiss", err)
+	}
+	if got, err := store.Get(ctx, "a"); err != nil || string(got) != "fresh" {
+		t.Errorf("Get() after replace = %q, %v, want the fresh value", got, err)
+	}
+	if store.Len() != 1 || store.Size() != 13 {
+		t.Errorf("Len(), Size() = %d, %d, want 1, 13 of the fresh file", store.Len(), store.Size())
+	}
+}
+
+func TestFileStoreRemovesTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"stremigo-123.tmp", "123.tmp", "other.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	if _, err := NewFileStore(dir, 0); err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "stremigo-123.tmp")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of unfinished temporary file error = %v, want it removed", err)
+	}
+	for _, name := range []string{"123.tmp", "other.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Stat() of other file %s error = %v, want it left alone", name, err)
+		}
+	}
+}
+
+func TestResponseCacheFileStore(t *testing.T) {
+	dir := t.TempDir()
+	streams := &cachedStreams{policy: CachePolicy{MaxAge: 60}}
+
+	for range 2 {
+		store, err := NewFileStore(dir, 0)
+		if err != nil {
+			t.Fatalf("NewFileStore() error = %v", err)
+		}
+		s := newCachedServer(t, streams, NewResponseCache(WithCacheStore(store)))
+
+		if code, title := getStreamTitle(s, "/token/stream/movie/tt0111161.json"); code != http.StatusOK || title != "1" {
+			t.Errorf("response = %d %q, want 200 \"1\"", code, title)
+		}
+	}
+
+	if got := streams.calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1 as the response outlives the restart", got)
+	}
+}
@@ -0,0 +1,142 @@
+package stremigo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxDeclaredManifests - how many manifests of distinct tokens filterUndeclared keeps
+const maxDeclaredManifests = 1024
+
+// DefaultIDFilterTTL - how long WithIDFilter keeps the manifest of a token before it is requested again
+const DefaultIDFilterTTL = 10 * time.Minute
+
+// WithIDFilter - answers requests of types, ids and catalogs not declared by the manifest with an empty
+// response without calling the provider, see AddonManifest.AcceptsID; the manifest is requested from the provider
+// once per token and kept for DefaultIDFilterTTL, see WithIDFilterTTL
+func WithIDFilter(filter bool) Option {
+	return func(s *Server) {
+		s.filterIDs = filter
+	}
+}
+
+// WithIDFilterTTL - how long WithIDFilter keeps the manifest of a token, so changes of the manifest, e.g. newly
+// declared types or prefixes, are applied after the TTL at the latest
+func WithIDFilterTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.declared.ttl = ttl
+	}
+}
+
+// declaredManifests - manifests of filterUndeclared keyed by token, kept for ttl; when full, the expired ones
+// are dropped, or an arbitrary one when none is expired
+type declaredManifests struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	manifests map[string]*declaredManifest
+}
+
+// declaredManifest - manifest of a token kept by declaredManifests
+type declaredManifest struct {
+	manifest *AddonManifest
+	expires  time.Time
+}
+
+// get - returns the manifest of the token, nil when it is missing or expired
+func (d *declaredManifests) get(token string) *AddonManifest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	declared, ok := d.manifests[token]
+	if !ok || !d.now().Before(declared.expires) {
+		return nil
+	}
+	return declared.manifest
+}
+
+func (d *declaredManifests) set(token string, manifest *AddonManifest) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.manifests == nil {
+		d.manifests = map[string]*declaredManifest{}
+	}
+
+	now := d.now()
+	if _, ok := d.manifests[token]; !ok && len(d.manifests) >= maxDeclaredManifests {
+		for key, declared := range d.manifests {
+			if !now.Before(declared.expires) {
+				delete(d.manifests, key)
+			}
+		}
+	}
+	if _, ok := d.manifests[token]; !ok && len(d.manifests) >= maxDeclaredManifests {
+		for key := range d.manifests {
+			delete(d.manifests, key)
+			break
+		}
+	}
+	d.manifests[token] = &declaredManifest{manifest: manifest, expires: now.Add(d.ttl)}
+}
+
+// filterUndeclared - ResourceMiddleware answering requests not accepted by AddonManifest.AcceptsID
+// with emptyResponse; requests pass when the manifest can't be loaded, the provider reports the error then
+func (s *Server) filterUndeclared(next ResourceHandler) ResourceHandler {
+	return func(ctx context.Context, req *ResourceRequest) (any, error) {
+		if req.Resource == PathManifest {
+			return next(ctx, req)
+		}
+
+		manifest := s.declared.get(req.Token)
+		if manifest == nil {
+			manifest = s.loadDeclared(ctx, req.Token)
+		}
+		if manifest == nil || manifest.AcceptsID(req.Resource, req.Type, req.ID) {
+			return next(ctx, req)
+		}
+
+		return emptyResponse(req.Resource)
+	}
+}
+
+// loadDeclared - requests the manifest of the token from the provider and keeps it, nil when it fails;
+// legacy providers write their manifest nowhere and validators set by the provider don't reach the response
+func (s *Server) loadDeclared(ctx context.Context, token string) *AddonManifest {
+	ctx = withValidators(ctx)
+	if e := exchangeFromContext(ctx); e != nil {
+		ctx = withExchange(ctx, discardResponseWriter{}, e.r)
+	}
+
+	manifest, err := s.provider.Manifest(ctx, token)
+	if err != nil || manifest == nil {
+		return nil
+	}
+	s.declared.set(token, manifest)
+	return manifest
+}
+
+// emptyMeta - empty meta response {"meta": null}, Meta has no empty form of its own
+type emptyMeta struct {
+	Meta *Meta `json:"meta"`
+}
+
+// emptyResponse - returns empty response of the resource
+func emptyResponse(resource string) (any, error) {
+	switch resource {
+	case PathAddonCatalog:
+		return &AddonCatalogList{Addons: []*AddonCatalog{}}, nil
+	case PathCatalog:
+		return &MetaPreviewList{Metas: []*MetaPreview{}}, nil
+	case PathMeta:
+		return &emptyMeta{}, nil
+	case PathStream:
+		return &StreamList{Streams: []*Stream{}}, nil
+	case PathSubtitles:
+		return &SubtitlesList{Subtitles: []*Subtitles{}}, nil
+	default:
+		return nil, ErrNotFound
+	}
+}
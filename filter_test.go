@@ -0,0 +1,182 @@
+package stremigo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// declaredProvider - mockContextProvider declaring imdb streams and kitsu series meta, counting manifest requests
+type declaredProvider struct {
+	mockContextProvider
+	manifests int
+}
+
+func (p *declaredProvider) Manifest(ctx context.Context, token string) (*AddonManifest, error) {
+	p.manifests++
+	SetETag(ctx, "manifest")
+	return &AddonManifest{
+		ID:       "com.example.declared",
+		Types:    []string{TypeMovie, TypeSeries},
+		Prefixes: []string{PrefixImdb},
+		Resources: []*Resource{
+			{Name: ResourceCatalog},
+			{Name: ResourceMeta, Type: []string{TypeSeries}, Prefixes: []string{PrefixKitsu}},
+			{Name: ResourceStream},
+		},
+		Catalogs: []*Catalog{{ID: "top", Type: TypeMovie, Name: "Top"}},
+	}, nil
+}
+
+func TestServerIDFilter(t *testing.T) {
+	calls := 0
+	counter := func(next ResourceHandler) ResourceHandler {
+		return func(ctx context.Context, req *ResourceRequest) (any, error) {
+			calls++
+			return next(ctx, req)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		wantCode  int
+		wantBody  string
+		wantCalls int
+	}{
+		{name: "declared stream", path: "/stream/movie/tt0111161.json", wantCode: http.StatusOK, wantCalls: 1},
+		{name: "undeclared prefix", path: "/stream/movie/kitsu:1.json", wantCode: http.StatusOK, wantBody: `{"streams":[]}`},
+		{name: "undeclared type", path: "/stream/channel/tt0111161.json", wantCode: http.StatusOK, wantBody: `{"streams":[]}`},
+		{name: "undeclared resource", path: "/subtitles/movie/tt0111161.json", wantCode: http.StatusOK, wantBody: `{"subtitles":[]}`},
+		{name: "declared catalog", path: "/catalog/movie/top.json", wantCode: http.StatusOK, wantCalls: 1},
+		{name: "undeclared catalog", path: "/catalog/movie/other.json", wantCode: http.StatusOK, wantBody: `{"metas":[]}`},
+		{name: "declared meta", path: "/meta/series/kitsu:1376.json", wantCode: http.StatusNotFound, wantCalls: 1},
+		{name: "undeclared meta", path: "/meta/series/tt0944947.json", wantCode: http.StatusOK, wantBody: `{"meta":null}`},
+		{name: "manifest", path: "/manifest.json", wantCode: http.StatusOK, wantCalls: 1},
+	}
+
+	provider := &declaredProvider{}
+	server := NewServer(provider, WithIDFilter(true), WithResourceMiddleware(counter))
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				calls = 0
+				rr := httptest.NewRecorder()
+
+				server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+				if rr.Code != tt.wantCode {
+					t.Errorf("status = %d, want %d", rr.Code, tt.wantCode)
+				}
+				if calls != tt.wantCalls {
+					t.Errorf("provider called %d times, want %d", calls, tt.wantCalls)
+				}
+				if tt.wantBody != "" && strings.TrimSpace(rr.Body.String()) != tt.wantBody {
+					t.Errorf("body = %s, want %s", rr.Body, tt.wantBody)
+				}
+				if etag := rr.Header().Get("ETag"); tt.path != "/manifest.json" && etag == `"manifest"` {
+					t.Errorf("ETag = %s, want ETag of the response rather than of the manifest", etag)
+				}
+			},
+		)
+	}
+
+	// the filter loads the manifest once, the last request is served by the provider
+	if provider.manifests != 2 {
+		t.Errorf("manifest requested %d times, want 2", provider.manifests)
+	}
+}
+
+// changingProvider - mockContextProvider declaring streams of the types, which may change between requests,
+// counting stream requests
+type changingProvider struct {
+	mockContextProvider
+	types   []string
+	streams int
+}
+
+func (p *changingProvider) Stream(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+	p.streams++
+	return p.mockContextProvider.Stream(ctx, token, args)
+}
+
+func (p *changingProvider) Manifest(ctx context.Context, token string) (*AddonManifest, error) {
+	return &AddonManifest{ID: "com.example.changing", Types: p.types, Resources: []*Resource{{Name: ResourceStream}}}, nil
+}
+
+func TestServerIDFilterTTL(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	provider := &changingProvider{types: []string{TypeMovie}}
+	server := NewServer(provider, WithIDFilter(true), WithIDFilterTTL(time.Minute))
+	server.declared.now = clock.Now
+
+	get := func() {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream/series/tt0944947.json", nil))
+	}
+
+	get()
+	provider.types = []string{TypeMovie, TypeSeries}
+	get()
+	if provider.streams != 0 {
+		t.Errorf("streams requested %d times within TTL, want 0 as the kept manifest declares no series", provider.streams)
+	}
+
+	clock.Add(time.Minute)
+	get()
+	if provider.streams != 1 {
+		t.Errorf("streams requested %d times after TTL, want 1 as the reloaded manifest declares series", provider.streams)
+	}
+}
+
+// legacyDeclaredProvider - mockProvider declaring imdb movies, writing a header whenever the manifest is requested
+type legacyDeclaredProvider struct {
+	mockProvider
+}
+
+func (p *legacyDeclaredProvider) GetManifest(w http.ResponseWriter, r *http.Request, token string) *AddonManifest {
+	w.Header().Set("X-Manifest", "written")
+	return &AddonManifest{
+		ID:        "com.example.legacy",
+		Types:     []string{TypeMovie},
+		Prefixes:  []string{PrefixImdb},
+		Resources: []*Resource{{Name: ResourceStream}},
+	}
+}
+
+func TestServerIDFilterLegacyProvider(t *testing.T) {
+	server := NewServer(AdaptProvider(&legacyDeclaredProvider{}), WithIDFilter(true))
+
+	for _, path := range []string{"/stream/movie/tt0111161.json", "/stream/movie/kitsu:1.json"} {
+		rr := httptest.NewRecorder()
+
+		server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", path, rr.Code, http.StatusOK)
+		}
+		if header := rr.Header().Get("X-Manifest"); header != "" {
+			t.Errorf("%s: X-Manifest = %q, want the manifest written nowhere", path, header)
+		}
+	}
+}
+
+func TestServerIDFilterDisabled(t *testing.T) {
+	called := false
+	spy := func(next ResourceHandler) ResourceHandler {
+		return func(ctx context.Context, req *ResourceRequest) (any, error) {
+			called = true
+			return next(ctx, req)
+		}
+	}
+
+	NewServer(&declaredProvider{}, WithResourceMiddleware(spy)).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/subtitles/movie/tt0111161.json", nil))
+
+	if !called {
+		t.Error("provider not called, want undeclared requests passed through by default")
+	}
+}
@@ -0,0 +1,238 @@
+package stremigo
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IDEpisodes - how a namespace encodes episodes in content ids
+type IDEpisodes int
+
+const (
+	// IDEpisodesSeasonal - {base}:{season}:{episode}, e.g. tt0944947:1:5
+	IDEpisodesSeasonal IDEpisodes = iota
+	// IDEpisodesNumbered - {base}:{episode}, e.g. kitsu:1376:5
+	IDEpisodesNumbered
+	// IDEpisodesNone - ids never carry episodes, everything after the prefix is the base id, e.g. yt_id:UCxyz
+	IDEpisodesNone
+)
+
+// IDNamespace - registered content id prefix
+// Name - name of the namespace, e.g. IDNamespaceImdb
+// Prefix - prefix of the ids, e.g. PrefixImdb
+// Episodes - encoding of episodes
+type IDNamespace struct {
+	Name     string
+	Prefix   string
+	Episodes IDEpisodes
+}
+
+var (
+	idNamespacesMu sync.RWMutex
+	idNamespaces   = []IDNamespace{
+		{Name: IDNamespaceImdb, Prefix: PrefixImdb, Episodes: IDEpisodesSeasonal},
+		{Name: IDNamespaceYoutube, Prefix: PrefixYoutube, Episodes: IDEpisodesNone},
+		{Name: IDNamespaceKitsu, Prefix: PrefixKitsu, Episodes: IDEpisodesNumbered},
+		{Name: IDNamespaceTmdb, Prefix: PrefixTmdb, Episodes: IDEpisodesSeasonal},
+		{Name: IDNamespaceMal, Prefix: PrefixMal, Episodes: IDEpisodesNumbered},
+	}
+)
+
+// RegisterIDNamespace - registers custom prefix recognised by ParseID, e.g. {Name: "filmon", Prefix: "filmon:"};
+// registering the name or prefix again replaces the previous namespace
+func RegisterIDNamespace(ns IDNamespace) error {
+	if ns.Name == "" || ns.Prefix == "" {
+		return errors.New("stremigo: id namespace requires name and prefix")
+	}
+
+	idNamespacesMu.Lock()
+	defer idNamespacesMu.Unlock()
+
+	idNamespaces = slices.DeleteFunc(idNamespaces, func(n IDNamespace) bool { return n.Name == ns.Name || n.Prefix == ns.Prefix })
+	idNamespaces = append(idNamespaces, ns)
+	return nil
+}
+
+// lookupIDNamespace - returns namespace with the longest prefix of the id
+func lookupIDNamespace(id string) (IDNamespace, bool) {
+	idNamespacesMu.RLock()
+	defer idNamespacesMu.RUnlock()
+
+	var found IDNamespace
+	for _, ns := range idNamespaces {
+		if strings.HasPrefix(id, ns.Prefix) && len(ns.Prefix) > len(found.Prefix) {
+			found = ns
+		}
+	}
+	return found, found.Name != ""
+}
+
+// namedIDNamespace - returns namespace of the name
+func namedIDNamespace(name string) (IDNamespace, bool) {
+	idNamespacesMu.RLock()
+	defer idNamespacesMu.RUnlock()
+
+	i := slices.IndexFunc(idNamespaces, func(ns IDNamespace) bool { return ns.Name == name })
+	if i < 0 {
+		return IDNamespace{}, false
+	}
+	return idNamespaces[i], true
+}
+
+// ContentID - parsed Stremio content id, e.g. tt0944947:1:5 is {imdb tt0944947 1 5}
+// Namespace - name of the registered namespace, empty for unknown prefix
+// BaseID - id of the title including the prefix, e.g. "tt0944947" or "kitsu:1376"
+// Season - season of the episode, 0 for ids without season
+// Episode - episode, 0 for ids of the title itself unless HasEpisode is set
+// HasEpisode - the id carries the episode, set by ParseID; it tells episode 0 of season 0 from the title itself
+type ContentID struct {
+	Namespace  string
+	BaseID     string
+	Season     int
+	Episode    int
+	HasEpisode bool
+}
+
+// IsEpisode - reports whether the id identifies an episode rather than the title
+func (c ContentID) IsEpisode() bool {
+	return c.HasEpisode || c.Season > 0 || c.Episode > 0
+}
+
+func (c ContentID) String() string {
+	return FormatID(c)
+}
+
+// ParseID - parses content id by the registered namespaces; ids of unknown namespaces are parsed as
+// {base}:{season}:{episode} when they end with two numeric segments. Fails with ErrBadRequest for empty ids,
+// bare prefixes and episodes not matching the namespace encoding.
+func ParseID(id string) (ContentID, error) {
+	if id == "" {
+		return ContentID{}, fmt.Errorf("%w: empty id", ErrBadRequest)
+	}
+
+	ns, known := lookupIDNamespace(id)
+	if !known {
+		return parseUnknownID(id), nil
+	}
+
+	rest := strings.TrimPrefix(id, ns.Prefix)
+	if rest == "" {
+		return ContentID{}, fmt.Errorf("%w: id %q has no value after prefix", ErrBadRequest, id)
+	}
+
+	cid := ContentID{Namespace: ns.Name, BaseID: id}
+	if ns.Episodes == IDEpisodesNone {
+		return cid, nil
+	}
+
+	value, episode, hasEpisode := strings.Cut(rest, ":")
+	if !hasEpisode {
+		return cid, nil
+	}
+	cid.BaseID, cid.HasEpisode = ns.Prefix+value, true
+
+	parts := strings.Split(episode, ":")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return ContentID{}, fmt.Errorf("%w: id %q has invalid episode", ErrBadRequest, id)
+		}
+		numbers[i] = n
+	}
+
+	switch {
+	case ns.Episodes == IDEpisodesSeasonal && len(numbers) == 2:
+		cid.Season, cid.Episode = numbers[0], numbers[1]
+	case ns.Episodes == IDEpisodesNumbered && len(numbers) == 1:
+		cid.Episode = numbers[0]
+	default:
+		return ContentID{}, fmt.Errorf("%w: id %q has invalid episode", ErrBadRequest, id)
+	}
+
+	return cid, nil
+}
+
+// parseUnknownID - parses id of unknown namespace, only {base}:{season}:{episode} is recognised
+func parseUnknownID(id string) ContentID {
+	cid := ContentID{BaseID: id}
+
+	parts := strings.Split(id, ":")
+	if len(parts) < 3 {
+		return cid
+	}
+
+	season, errSeason := strconv.Atoi(parts[len(parts)-2])
+	episode, errEpisode := strconv.Atoi(parts[len(parts)-1])
+	if errSeason != nil || errEpisode != nil || season < 0 || episode < 0 {
+		return cid
+	}
+
+	cid.BaseID = strings.Join(parts[:len(parts)-2], ":")
+	cid.Season, cid.Episode, cid.HasEpisode = season, episode, true
+	return cid
+}
+
+// FormatID - formats the id by encoding of its namespace, the inverse of ParseID
+func FormatID(id ContentID) string {
+	if !id.IsEpisode() {
+		return id.BaseID
+	}
+
+	episodes := IDEpisodesSeasonal
+	if ns, ok := namedIDNamespace(id.Namespace); ok {
+		episodes = ns.Episodes
+	}
+
+	switch episodes {
+	case IDEpisodesNumbered:
+		return id.BaseID + ":" + strconv.Itoa(id.Episode)
+	case IDEpisodesNone:
+		return id.BaseID
+	default:
+		return id.BaseID + ":" + strconv.Itoa(id.Season) + ":" + strconv.Itoa(id.Episode)
+	}
+}
+
+// AcceptsID - reports whether the manifest declares the resource for the content type and id, the way
+// Stremio selects addons: by Resource.Type and Resource.Prefixes, falling back to AddonManifest.Types
+// and AddonManifest.Prefixes. Catalogs are matched by AddonManifest.Catalogs and AddonCatalogs instead.
+func (m *AddonManifest) AcceptsID(resource, typ, id string) bool {
+	switch resource {
+	case ResourceCatalog:
+		return slices.ContainsFunc(m.Catalogs, func(c *Catalog) bool { return c != nil && c.Type == typ && c.ID == id })
+	case ResourceAddonCatalog:
+		return slices.ContainsFunc(m.AddonCatalogs, func(c *Catalog) bool { return c != nil && c.Type == typ && c.ID == id })
+	}
+
+	for _, r := range m.Resources {
+		if r == nil || r.Name != resource {
+			continue
+		}
+
+		types, prefixes := r.Type, r.Prefixes
+		if len(types) == 0 {
+			types = m.Types
+		}
+		if len(prefixes) == 0 {
+			prefixes = m.Prefixes
+		}
+
+		if slices.Contains(types, typ) && hasIDPrefix(prefixes, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasIDPrefix - reports whether the id has one of the prefixes, empty prefixes accept every id
+func hasIDPrefix(prefixes []string, id string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(prefixes, func(prefix string) bool { return strings.HasPrefix(id, prefix) })
+}
@@ -0,0 +1,127 @@
+package stremigo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		want    ContentID
+		wantErr bool
+	}{
+		{name: "imdb movie", id: "tt0111161", want: ContentID{Namespace: IDNamespaceImdb, BaseID: "tt0111161"}},
+		{name: "imdb episode", id: "tt0944947:2:5", want: ContentID{Namespace: IDNamespaceImdb, BaseID: "tt0944947", Season: 2, Episode: 5, HasEpisode: true}},
+		{name: "imdb special", id: "tt0944947:0:1", want: ContentID{Namespace: IDNamespaceImdb, BaseID: "tt0944947", Season: 0, Episode: 1, HasEpisode: true}},
+		{
+			name: "imdb episode zero",
+			id:   "tt0944947:1:0",
+			want: ContentID{Namespace: IDNamespaceImdb, BaseID: "tt0944947", Season: 1, HasEpisode: true},
+		},
+		{
+			name: "imdb season zero episode zero",
+			id:   "tt0944947:0:0",
+			want: ContentID{Namespace: IDNamespaceImdb, BaseID: "tt0944947", HasEpisode: true},
+		},
+		{name: "kitsu anime", id: "kitsu:1376", want: ContentID{Namespace: IDNamespaceKitsu, BaseID: "kitsu:1376"}},
+		{name: "kitsu episode", id: "kitsu:1376:12", want: ContentID{Namespace: IDNamespaceKitsu, BaseID: "kitsu:1376", Episode: 12, HasEpisode: true}},
+		{name: "kitsu episode zero", id: "kitsu:1376:0", want: ContentID{Namespace: IDNamespaceKitsu, BaseID: "kitsu:1376", HasEpisode: true}},
+		{name: "tmdb episode", id: "tmdb:1399:1:1", want: ContentID{Namespace: IDNamespaceTmdb, BaseID: "tmdb:1399", Season: 1, Episode: 1, HasEpisode: true}},
+		{name: "youtube", id: "yt_id:UCxyz:abc", want: ContentID{Namespace: IDNamespaceYoutube, BaseID: "yt_id:UCxyz:abc"}},
+		{name: "unknown", id: "com.example:42", want: ContentID{BaseID: "com.example:42"}},
+		{name: "unknown episode", id: "com.example:42:3:4", want: ContentID{BaseID: "com.example:42", Season: 3, Episode: 4, HasEpisode: true}},
+		{name: "unknown episode zero", id: "com.example:42:0:0", want: ContentID{BaseID: "com.example:42", HasEpisode: true}},
+		{name: "empty", id: "", wantErr: true},
+		{name: "bare prefix", id: "kitsu:", wantErr: true},
+		{name: "imdb without season", id: "tt0944947:5", wantErr: true},
+		{name: "kitsu with season", id: "kitsu:1376:1:2", wantErr: true},
+		{name: "non-numeric episode", id: "tt0944947:one:two", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := ParseID(tt.id)
+				if tt.wantErr {
+					if !errors.Is(err, ErrBadRequest) {
+						t.Errorf("ParseID(%q) error = %v, want ErrBadRequest", tt.id, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("ParseID(%q) error = %v", tt.id, err)
+				}
+				if got != tt.want {
+					t.Errorf("ParseID(%q) = %+v, want %+v", tt.id, got, tt.want)
+				}
+				if formatted := FormatID(got); formatted != tt.id {
+					t.Errorf("FormatID(%+v) = %q, want %q", got, formatted, tt.id)
+				}
+			},
+		)
+	}
+}
+
+func TestRegisterIDNamespace(t *testing.T) {
+	if err := RegisterIDNamespace(IDNamespace{Name: "filmon"}); err == nil {
+		t.Error("RegisterIDNamespace() without prefix succeeded, want error")
+	}
+
+	if err := RegisterIDNamespace(IDNamespace{Name: "filmon", Prefix: "filmon:", Episodes: IDEpisodesNumbered}); err != nil {
+		t.Fatalf("RegisterIDNamespace() error = %v", err)
+	}
+
+	got, err := ParseID("filmon:abc:7")
+	if err != nil {
+		t.Fatalf("ParseID() error = %v", err)
+	}
+	if want := (ContentID{Namespace: "filmon", BaseID: "filmon:abc", Episode: 7, HasEpisode: true}); got != want {
+		t.Errorf("ParseID() = %+v, want %+v", got, want)
+	}
+	if got.String() != "filmon:abc:7" {
+		t.Errorf("String() = %q, want %q", got.String(), "filmon:abc:7")
+	}
+}
+
+func TestManifestAcceptsID(t *testing.T) {
+	manifest := &AddonManifest{
+		Types:    []string{TypeMovie, TypeSeries},
+		Prefixes: []string{PrefixImdb},
+		Resources: []*Resource{
+			{Name: ResourceCatalog},
+			{Name: ResourceMeta, Type: []string{TypeSeries}, Prefixes: []string{PrefixKitsu}},
+			{Name: ResourceStream},
+		},
+		Catalogs: []*Catalog{{ID: "top", Type: TypeMovie}},
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		typ      string
+		id       string
+		want     bool
+	}{
+		{name: "stream by manifest prefixes", resource: ResourceStream, typ: TypeMovie, id: "tt0111161", want: true},
+		{name: "stream of other prefix", resource: ResourceStream, typ: TypeMovie, id: "kitsu:1", want: false},
+		{name: "stream of other type", resource: ResourceStream, typ: TypeChannel, id: "tt0111161", want: false},
+		{name: "meta by resource prefixes", resource: ResourceMeta, typ: TypeSeries, id: "kitsu:1376", want: true},
+		{name: "meta of manifest prefix", resource: ResourceMeta, typ: TypeSeries, id: "tt0944947", want: false},
+		{name: "meta of other type", resource: ResourceMeta, typ: TypeMovie, id: "kitsu:1376", want: false},
+		{name: "undeclared resource", resource: ResourceSubtitles, typ: TypeMovie, id: "tt0111161", want: false},
+		{name: "declared catalog", resource: ResourceCatalog, typ: TypeMovie, id: "top", want: true},
+		{name: "undeclared catalog", resource: ResourceCatalog, typ: TypeSeries, id: "top", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := manifest.AcceptsID(tt.resource, tt.typ, tt.id); got != tt.want {
+					t.Errorf("AcceptsID(%q, %q, %q) = %v, want %v", tt.resource, tt.typ, tt.id, got, tt.want)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,125 @@
+package stremigo
+
+import (
+	"context"
+)
+
+// ResourceRequest - parsed request of a resource passed through ResourceMiddleware
+// Resource - requested resource, one of PathManifest, PathAddonCatalog, PathCatalog, PathMeta, PathStream, PathSubtitles
+// Token - token of secured addon, empty for unsecured one
+// Type - requested content type, empty for manifest
+// ID - requested id, empty for manifest
+// Extra - extra properties of the request, empty for manifest
+// Middleware may rewrite Type, ID and Extra, the typed arguments of ContextProvider are built from them when
+// the provider is called.
+type ResourceRequest struct {
+	Resource string
+	Token    string
+	Type     string
+	ID       string
+	Extra    ExtraArgs
+}
+
+// newResourceRequest - parses escaped {type}/{id}[/{extra}].json segments following the resource name
+func newResourceRequest(resource, token string, segments []string) (*ResourceRequest, error) {
+	req := &ResourceRequest{Resource: resource, Token: token}
+	if resource == PathManifest {
+		if len(segments) > 0 {
+			return nil, ErrInvalidPath
+		}
+		return req, nil
+	}
+
+	rp, err := parseResourcePath(segments)
+	if err != nil {
+		return nil, err
+	}
+	req.Type, req.ID, req.Extra = rp.Type, rp.ID, rp.Extra
+
+	// the arguments are checked before the middleware, so malformed requests don't reach it
+	if _, err = req.args(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// args - returns typed arguments of ContextProvider (*CatalogArgs, *MetaArgs, ...) built from Type, ID and Extra,
+// nil for manifest
+func (req *ResourceRequest) args() (any, error) {
+	rp := &resourcePath{Type: req.Type, ID: req.ID, Extra: req.Extra}
+
+	switch req.Resource {
+	case PathManifest:
+		return nil, nil
+	case PathAddonCatalog:
+		return &AddonCatalogArgs{Type: rp.Type, ID: rp.ID, Extra: rp.Extra}, nil
+	case PathCatalog:
+		return &CatalogArgs{Type: rp.Type, ID: rp.ID, Extra: rp.Extra}, nil
+	case PathMeta:
+		return &MetaArgs{Type: rp.Type, ID: rp.ID, Extra: rp.Extra}, nil
+	case PathStream:
+		return &StreamArgs{Type: rp.Type, ID: rp.ID, Extra: rp.Extra}, nil
+	case PathSubtitles:
+		return newSubtitlesArgs(rp)
+	default:
+		return nil, ErrNotFound
+	}
+}
+
+// ResourceHandler - serves ResourceRequest; the response is the typed result of ContextProvider,
+// i.e. *AddonManifest, *AddonCatalogList, *MetaPreviewList, *Meta, *StreamList or *SubtitlesList.
+// A nil response without an error is answered as ErrNotFound. Undeclared meta filtered by WithIDFilter
+// is answered with {"meta": null}, which is none of the typed results.
+type ResourceHandler func(ctx context.Context, req *ResourceRequest) (any, error)
+
+// ResourceMiddleware - wraps ResourceHandler; it may inspect the request, short-circuit it by returning
+// its own response or error without calling next, or rewrite the response of next before it is encoded
+type ResourceMiddleware func(next ResourceHandler) ResourceHandler
+
+// WithResourceMiddleware - wraps provider calls by the middleware; the first middleware is the outermost one
+func WithResourceMiddleware(middleware ...ResourceMiddleware) Option {
+	return func(s *Server) {
+		s.resourceMiddleware = append(s.resourceMiddleware, middleware...)
+	}
+}
+
+// chainResource - wraps the handler by the middleware, the first middleware is the outermost one
+func chainResource(handler ResourceHandler, middleware []ResourceMiddleware) ResourceHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// dispatch - calls ContextProvider method of the requested resource
+func (s *Server) dispatch(ctx context.Context, req *ResourceRequest) (any, error) {
+	p := s.provider
+
+	args, err := req.args()
+	if err != nil {
+		return nil, err
+	}
+
+	switch args := args.(type) {
+	case *AddonCatalogArgs:
+		ap, ok := p.(AddonCatalogContextProvider)
+		if !ok {
+			return nil, ErrNotFound
+		}
+		return result(ap.AddonCatalog(ctx, req.Token, args))
+	case *CatalogArgs:
+		return result(p.Catalog(ctx, req.Token, args))
+	case *MetaArgs:
+		return result(p.Meta(ctx, req.Token, args))
+	case *StreamArgs:
+		return result(p.Stream(ctx, req.Token, args))
+	case *SubtitlesArgs:
+		return result(p.Subtitles(ctx, req.Token, args))
+	}
+
+	manifest, err := p.Manifest(ctx, req.Token)
+	if err == nil && manifest != nil && s.validateManifest {
+		err = manifest.Validate()
+	}
+	return result(manifest, err)
+}
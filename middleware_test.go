@@ -0,0 +1,138 @@
+package stremigo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestResourceMiddlewareRequest(t *testing.T) {
+	var got *ResourceRequest
+
+	s := NewServer(
+		AdaptProvider(&mockProvider{secured: true}),
+		WithResourceMiddleware(
+			func(next ResourceHandler) ResourceHandler {
+				return func(ctx context.Context, req *ResourceRequest) (any, error) {
+					got = req
+					return next(ctx, req)
+				}
+			},
+		),
+	)
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/token/catalog/movie/top/genre=Action.json", nil))
+
+	want := &ResourceRequest{
+		Resource: PathCatalog,
+		Token:    "token",
+		Type:     TypeMovie,
+		ID:       "top",
+		Extra:    ExtraArgs{CatalogExtraGenre: {"Action"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("request = %+v, want %+v", got, want)
+	}
+}
+
+func TestResourceMiddlewareRewriteRequest(t *testing.T) {
+	p := &mockProvider{}
+
+	s := NewServer(
+		AdaptProvider(p),
+		WithResourceMiddleware(
+			func(next ResourceHandler) ResourceHandler {
+				return func(ctx context.Context, req *ResourceRequest) (any, error) {
+					req.Type, req.ID = TypeSeries, "tt0903747:1:1"
+					req.Extra = ExtraArgs{SubtitlesExtraVideoSize: {"42"}}
+					return next(ctx, req)
+				}
+			},
+		),
+	)
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/subtitles/movie/tt0111161.json", nil))
+
+	want := &SubtitlesArgs{
+		Type:      TypeSeries,
+		ID:        "tt0903747:1:1",
+		Extra:     ExtraArgs{SubtitlesExtraVideoSize: {"42"}},
+		VideoSize: 42,
+	}
+	if !reflect.DeepEqual(p.args, want) {
+		t.Errorf("args = %+v, want %+v", p.args, want)
+	}
+}
+
+func TestResourceMiddlewareShortCircuit(t *testing.T) {
+	p := &mockProvider{}
+
+	s := NewServer(
+		AdaptProvider(p),
+		WithResourceMiddleware(
+			func(next ResourceHandler) ResourceHandler {
+				return func(ctx context.Context, req *ResourceRequest) (any, error) {
+					if req.Resource == PathStream && req.ID == "blocked" {
+						return nil, ErrUnauthorized
+					}
+					return next(ctx, req)
+				}
+			},
+		),
+	)
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stream/movie/blocked.json", nil))
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	if p.args != nil {
+		t.Errorf("provider was called with %+v", p.args)
+	}
+}
+
+func TestResourceMiddlewareRewrite(t *testing.T) {
+	var order []string
+
+	trace := func(name string) ResourceMiddleware {
+		return func(next ResourceHandler) ResourceHandler {
+			return func(ctx context.Context, req *ResourceRequest) (any, error) {
+				order = append(order, name)
+				return next(ctx, req)
+			}
+		}
+	}
+
+	rewrite := func(next ResourceHandler) ResourceHandler {
+		return func(ctx context.Context, req *ResourceRequest) (any, error) {
+			data, err := next(ctx, req)
+			if list, ok := data.(*StreamList); ok {
+				list.Streams = append(list.Streams, &Stream{URL: "https://example.com/" + req.ID + ".mp4", Name: "rewritten"})
+				list.CacheMaxAge = 60
+			}
+			return data, err
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	NewServer(AdaptProvider(&mockProvider{}), WithResourceMiddleware(trace("outer"), rewrite, trace("inner"))).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stream/movie/tt0111161.json", nil))
+
+	var got StreamList
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(got.Streams) != 1 || got.Streams[0].Name != "rewritten" {
+		t.Errorf("streams = %+v, want the rewritten stream", got.Streams)
+	}
+	if header := rr.Header().Get("Cache-Control"); header != "max-age=60, public" {
+		t.Errorf("Cache-Control = %q, want %q", header, "max-age=60, public")
+	}
+	if !reflect.DeepEqual(order, []string{"outer", "inner"}) {
+		t.Errorf("order = %v, want [outer inner]", order)
+	}
+}
@@ -0,0 +1,162 @@
+package stremigo
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// StreamFilter - reports whether ProxyProvider keeps the stream
+type StreamFilter func(s *Stream) bool
+
+// MetaPreviewFilter - reports whether ProxyProvider keeps the catalog item
+type MetaPreviewFilter func(m *MetaPreview) bool
+
+// ProxyOption - configures ProxyProvider created by NewProxyProvider
+type ProxyOption func(p *ProxyProvider)
+
+// ProxyProvider - ContextProvider re-exposing a remote addon, e.g. to sanitise a third-party addon without
+// forking it. The manifest is rewritten by WithManifestRewrite, streams and catalog items are filtered by
+// WithStreamFilter and WithMetaPreviewFilter, and responses are cached by AddonClient.
+type ProxyProvider struct {
+	client        *AddonClient
+	clientOptions []ClientOption
+	rewrite       []func(m *AddonManifest)
+	streams       []StreamFilter
+	metas         []MetaPreviewFilter
+}
+
+// NewProxyProvider - creates ProxyProvider of the addon at the transport URL; responses are cached for
+// their cacheMaxAge, responses setting none are not cached unless WithClientDefaultTTL is set
+func NewProxyProvider(transportURL string, opts ...ProxyOption) (*ProxyProvider, error) {
+	p := &ProxyProvider{}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	client, err := NewAddonClient(transportURL, p.clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	p.client = client
+
+	return p, nil
+}
+
+// WithProxyClientOptions - configures AddonClient of the remote addon, e.g. by WithClientTimeout
+// or WithClientDefaultTTL
+func WithProxyClientOptions(opts ...ClientOption) ProxyOption {
+	return func(p *ProxyProvider) {
+		p.clientOptions = append(p.clientOptions, opts...)
+	}
+}
+
+// WithManifestRewrite - modifies copy of the remote manifest before it is served, e.g. sets own ID, Name and Logo,
+// so Stremio doesn't take the proxy for the original addon
+func WithManifestRewrite(rewrite func(m *AddonManifest)) ProxyOption {
+	return func(p *ProxyProvider) {
+		p.rewrite = append(p.rewrite, rewrite)
+	}
+}
+
+// WithStreamFilter - drops streams for which any of the filters returns false, see WebReadyStream
+// and StreamAvailableIn
+func WithStreamFilter(filters ...StreamFilter) ProxyOption {
+	return func(p *ProxyProvider) {
+		p.streams = append(p.streams, filters...)
+	}
+}
+
+// WithMetaPreviewFilter - drops catalog items for which any of the filters returns false
+func WithMetaPreviewFilter(filters ...MetaPreviewFilter) ProxyOption {
+	return func(p *ProxyProvider) {
+		p.metas = append(p.metas, filters...)
+	}
+}
+
+// WebReadyStream - StreamFilter dropping streams marked by StreamBehaviorHints.NotWebReady
+func WebReadyStream(s *Stream) bool {
+	return s.BehaviorHints == nil || !s.BehaviorHints.NotWebReady
+}
+
+// StreamAvailableIn - returns StreamFilter dropping streams whose StreamBehaviorHints.CountryWhitelist
+// doesn't contain the ISO 3166-1 alpha-3 country code, compared case-insensitively; streams without
+// the whitelist are kept
+func StreamAvailableIn(country string) StreamFilter {
+	return func(s *Stream) bool {
+		if s.BehaviorHints == nil || len(s.BehaviorHints.CountryWhitelist) == 0 {
+			return true
+		}
+		return slices.ContainsFunc(s.BehaviorHints.CountryWhitelist, func(c string) bool { return strings.EqualFold(c, country) })
+	}
+}
+
+// keep - reports whether all filters keep the item
+func keep[T any, F ~func(T) bool](filters []F, item T) bool {
+	for _, filter := range filters {
+		if !filter(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// Manifest - returns the remote manifest modified by WithManifestRewrite
+func (p *ProxyProvider) Manifest(ctx context.Context, token string) (*AddonManifest, error) {
+	manifest, err := p.client.Manifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rewrite := range p.rewrite {
+		rewrite(manifest)
+	}
+	return manifest, nil
+}
+
+// AddonCatalog - returns the remote addon catalog
+func (p *ProxyProvider) AddonCatalog(ctx context.Context, token string, args *AddonCatalogArgs) (*AddonCatalogList, error) {
+	return p.client.AddonCatalog(ctx, args)
+}
+
+// Catalog - returns the remote catalog without items dropped by WithMetaPreviewFilter
+func (p *ProxyProvider) Catalog(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+	list, err := p.client.Catalog(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Metas = slices.DeleteFunc(list.Metas, func(m *MetaPreview) bool { return m == nil || !keep(p.metas, m) })
+	return list, nil
+}
+
+// Meta - returns the remote meta
+func (p *ProxyProvider) Meta(ctx context.Context, token string, args *MetaArgs) (*Meta, error) {
+	return p.client.Meta(ctx, args)
+}
+
+// Stream - returns the remote streams without streams dropped by WithStreamFilter
+func (p *ProxyProvider) Stream(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+	list, err := p.client.Stream(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Streams = slices.DeleteFunc(list.Streams, func(s *Stream) bool { return s == nil || !keep(p.streams, s) })
+	return list, nil
+}
+
+// Subtitles - returns the remote subtitles
+func (p *ProxyProvider) Subtitles(ctx context.Context, token string, args *SubtitlesArgs) (*SubtitlesList, error) {
+	return p.client.Subtitles(ctx, args)
+}
+
+func (p *ProxyProvider) RenderConfigurePage(w http.ResponseWriter, r *http.Request, token string) {
+	WriteErrorResponse(w, NewErrorResponse(ErrNotFound, DefaultErrorMessages))
+}
+
+func (p *ProxyProvider) IsSecured() bool {
+	return false
+}
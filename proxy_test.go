@@ -0,0 +1,178 @@
+package stremigo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRemoteAddon - serves third-party addon with streams of mixed quality and counts the requests
+func newRemoteAddon(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	addon, err := NewAddonBuilder(AddonManifest{ID: "org.thirdparty", Version: "2.0.0", Name: "Third party", Description: "Remote addon", Logo: "https://thirdparty.org/logo.png"}).
+		DefineCatalogHandler(
+			&Catalog{ID: "top", Type: TypeMovie, Name: "Top"},
+			func(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+				return &MetaPreviewList{Metas: []*MetaPreview{{ID: "tt1", Name: "Family"}, {ID: "tt2", Name: "Adult", Genres: []string{"Adult"}}}}, nil
+			},
+		).
+		DefineStreamHandler(
+			[]string{TypeMovie}, nil,
+			func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+				return &StreamList{
+					Streams: []*Stream{
+						{Name: "web", URL: "https://thirdparty.org/a.mp4"},
+						{Name: "mkv", URL: "http://thirdparty.org/a.mkv", BehaviorHints: &StreamBehaviorHints{NotWebReady: true}},
+						{Name: "cze", URL: "https://thirdparty.org/cz.mp4", BehaviorHints: &StreamBehaviorHints{CountryWhitelist: []string{"cze"}}},
+						{Name: "usa", URL: "https://thirdparty.org/us.mp4", BehaviorHints: &StreamBehaviorHints{CountryWhitelist: []string{"usa"}}},
+					},
+				}, nil
+			},
+		).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	hits := &atomic.Int32{}
+	server := NewServer(addon)
+	srv := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				server.ServeHTTP(w, r)
+			},
+		),
+	)
+	t.Cleanup(srv.Close)
+	return srv, hits
+}
+
+func TestProxyProvider(t *testing.T) {
+	remote, hits := newRemoteAddon(t)
+
+	proxy, err := NewProxyProvider(
+		remote.URL+"/manifest.json",
+		WithManifestRewrite(
+			func(m *AddonManifest) {
+				m.ID, m.Name, m.Logo = "com.example.sanitised", "Sanitised", ""
+			},
+		),
+		WithStreamFilter(WebReadyStream, StreamAvailableIn("CZE")),
+		WithMetaPreviewFilter(
+			func(m *MetaPreview) bool {
+				return len(m.Genres) == 0 || m.Genres[0] != "Adult"
+			},
+		),
+		WithProxyClientOptions(WithClientDefaultTTL(time.Minute)),
+	)
+	if err != nil {
+		t.Fatalf("NewProxyProvider() error = %v", err)
+	}
+
+	srv := httptest.NewServer(NewServer(proxy))
+	defer srv.Close()
+
+	get := func(path string, v any) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s status = %d", path, resp.StatusCode)
+		}
+		if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("GET %s decode error = %v", path, err)
+		}
+	}
+
+	var manifest AddonManifest
+	get("/manifest.json", &manifest)
+	if manifest.ID != "com.example.sanitised" || manifest.Name != "Sanitised" || manifest.Logo != "" || manifest.Version != "2.0.0" {
+		t.Errorf("manifest = %+v, want rewritten id, name and logo", manifest)
+	}
+
+	var streams StreamList
+	get("/stream/movie/tt1.json", &streams)
+	var names []string
+	for _, s := range streams.Streams {
+		names = append(names, s.Name)
+	}
+	if want := []string{"web", "cze"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("streams = %v, want %v", names, want)
+	}
+
+	var catalog MetaPreviewList
+	get("/catalog/movie/top.json", &catalog)
+	if len(catalog.Metas) != 1 || catalog.Metas[0].ID != "tt1" {
+		t.Errorf("metas = %+v, want the family movie only", catalog.Metas)
+	}
+
+	before := hits.Load()
+	get("/stream/movie/tt1.json", &streams)
+	get("/manifest.json", &manifest)
+	if got := hits.Load(); got != before {
+		t.Errorf("remote requests = %d, want %d as responses are cached", got, before)
+	}
+	if manifest.ID != "com.example.sanitised" {
+		t.Errorf("cached manifest id = %q, want the rewritten one", manifest.ID)
+	}
+}
+
+func TestStreamAvailableIn(t *testing.T) {
+	filter := StreamAvailableIn("cze")
+
+	tests := []struct {
+		name   string
+		stream *Stream
+		want   bool
+	}{
+		{name: "no hints", stream: &Stream{}, want: true},
+		{name: "no whitelist", stream: &Stream{BehaviorHints: &StreamBehaviorHints{}}, want: true},
+		{name: "whitelisted", stream: &Stream{BehaviorHints: &StreamBehaviorHints{CountryWhitelist: []string{"svk", "cze"}}}, want: true},
+		{name: "whitelisted in upper case", stream: &Stream{BehaviorHints: &StreamBehaviorHints{CountryWhitelist: []string{"CZE"}}}, want: true},
+		{name: "not whitelisted", stream: &Stream{BehaviorHints: &StreamBehaviorHints{CountryWhitelist: []string{"usa"}}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := filter(tt.stream); got != tt.want {
+					t.Errorf("StreamAvailableIn() = %v, want %v", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestProxyProviderDefaults(t *testing.T) {
+	remote, hits := newRemoteAddon(t)
+
+	proxy, err := NewProxyProvider(remote.URL)
+	if err != nil {
+		t.Fatalf("NewProxyProvider() error = %v", err)
+	}
+
+	for range 2 {
+		if _, err = proxy.Stream(context.Background(), "", &StreamArgs{Type: TypeMovie, ID: "tt1"}); err != nil {
+			t.Fatalf("Stream() error = %v", err)
+		}
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("remote requests = %d, want 2 as streams set no cacheMaxAge", got)
+	}
+
+	rr := httptest.NewRecorder()
+	NewServer(proxy).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+PathConfigure, nil))
+	if ct := rr.Header().Get("Content-Type"); rr.Code != http.StatusNotFound || ct != "application/json" {
+		t.Errorf("configure page = %d %q, want 404 with the JSON error envelope", rr.Code, ct)
+	}
+}
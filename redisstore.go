@@ -0,0 +1,260 @@
+package stremigo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// DefaultRedisPoolSize - how many idle connections RedisStore keeps by default
+const DefaultRedisPoolSize = 10
+
+// DefaultRedisKeyPrefix - prefix RedisStore adds to the keys by default
+const DefaultRedisKeyPrefix = "stremigo:"
+
+// RedisOption - configures RedisStore created by NewRedisStore
+type RedisOption func(s *RedisStore)
+
+// RedisStore - CacheStore keeping the values in Redis, or any server speaking its protocol (RESP), so the cache
+// is shared by all replicas of the addon; values expire by Redis itself
+type RedisStore struct {
+	addr     string
+	password string
+	db       int
+	prefix   string
+	dialer   net.Dialer
+	pool     chan *redisConn
+}
+
+// redisConn - connection to Redis with buffered reader of the replies
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// redisError - error reply of Redis, the connection is still usable
+type redisError string
+
+func (e redisError) Error() string {
+	return "stremigo: redis: " + string(e)
+}
+
+// NewRedisStore - creates RedisStore of the server at the address, e.g. "localhost:6379"; connections are
+// opened on demand
+func NewRedisStore(addr string, opts ...RedisOption) *RedisStore {
+	s := &RedisStore{
+		addr:   addr,
+		prefix: DefaultRedisKeyPrefix,
+		dialer: net.Dialer{Timeout: 5 * time.Second},
+		pool:   make(chan *redisConn, DefaultRedisPoolSize),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// WithRedisPassword - authenticates the connections by AUTH
+func WithRedisPassword(password string) RedisOption {
+	return func(s *RedisStore) {
+		s.password = password
+	}
+}
+
+// WithRedisDB - selects the database of the connections by SELECT
+func WithRedisDB(db int) RedisOption {
+	return func(s *RedisStore) {
+		s.db = db
+	}
+}
+
+// WithRedisKeyPrefix - replaces DefaultRedisKeyPrefix, e.g. to separate caches of several addons
+func WithRedisKeyPrefix(prefix string) RedisOption {
+	return func(s *RedisStore) {
+		s.prefix = prefix
+	}
+}
+
+// WithRedisPoolSize - replaces DefaultRedisPoolSize
+func WithRedisPoolSize(size int) RedisOption {
+	return func(s *RedisStore) {
+		s.pool = make(chan *redisConn, max(size, 0))
+	}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := s.do(ctx, "GET", s.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrCacheMiss
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("stremigo: redis: unexpected GET reply %v", reply)
+	}
+	return value, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []any{"SET", s.prefix + key, value}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := s.do(ctx, args...)
+	return err
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", s.prefix+key)
+	return err
+}
+
+// Close - closes the idle connections
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case c := <-s.pool:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do - sends the command and returns its reply; the connection is reused unless it failed
+func (s *RedisStore) do(ctx context.Context, args ...any) (any, error) {
+	c, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.do(ctx, args...)
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) {
+		c.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case s.pool <- c:
+	default:
+		c.conn.Close()
+	}
+	return reply, err
+}
+
+// conn - returns an idle connection, or dials a new one authenticated and with the database selected
+func (s *RedisStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-s.pool:
+		return c, nil
+	default:
+	}
+
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+
+	if s.password != "" {
+		if _, err = c.do(ctx, "AUTH", s.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err = c.do(ctx, "SELECT", strconv.Itoa(s.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// do - writes the command as RESP array of bulk strings and reads the reply within the deadline of the context
+func (c *redisConn) do(ctx context.Context, args ...any) (any, error) {
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case string:
+			b = []byte(v)
+		case []byte:
+			b = v
+		}
+		buf = append(buf, "$"+strconv.Itoa(len(b))+"\r\n"...)
+		buf = append(append(buf, b...), "\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return readRESP(c.r)
+}
+
+// readRESP - reads a reply: simple string as string, error as redisError, integer as int64, bulk string
+// as []byte, array as []any, and null as nil
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("stremigo: redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$', '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("stremigo: redis: malformed reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		if kind == '*' {
+			// error items are kept as values, so the rest of the array is read and the connection stays usable
+			items := make([]any, n)
+			for i := range items {
+				items[i], err = readRESP(r)
+				var rerr redisError
+				if errors.As(err, &rerr) {
+					items[i], err = rerr, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+			return items, nil
+		}
+		b := make([]byte, n+2)
+		if _, err = io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	default:
+		return nil, fmt.Errorf("stremigo: redis: malformed reply %q", line)
+	}
+}
@@ -0,0 +1,177 @@
+package stremigo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// redisStandIn - local server speaking the subset of Redis protocol used by RedisStore
+type redisStandIn struct {
+	password string
+
+	mu       sync.Mutex
+	values   map[string][]byte
+	expires  map[string]time.Time
+	commands []string
+}
+
+// newRedisStandIn - starts redisStandIn requiring the password when set and returns its address
+func newRedisStandIn(t *testing.T, password string) (*redisStandIn, string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	r := &redisStandIn{password: password, values: map[string][]byte{}, expires: map[string]time.Time{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r, l.Addr().String()
+}
+
+func (r *redisStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := r.password == ""
+
+	for {
+		cmd, err := readRESP(reader)
+		if err != nil {
+			return
+		}
+		items, _ := cmd.([]any)
+		var args []string
+		for _, item := range items {
+			b, _ := item.([]byte)
+			args = append(args, string(b))
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		r.mu.Lock()
+		r.commands = append(r.commands, strings.Join(args, " "))
+		reply := "-ERR unknown command\r\n"
+		switch name := strings.ToUpper(args[0]); {
+		case name == "AUTH" && args[1] == r.password:
+			authenticated, reply = true, "+OK\r\n"
+		case name == "AUTH":
+			reply = "-WRONGPASS invalid password\r\n"
+		case !authenticated:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case name == "SELECT":
+			reply = "+OK\r\n"
+		case name == "GET":
+			value, ok := r.values[args[1]]
+			if exp, has := r.expires[args[1]]; has && time.Now().After(exp) {
+				ok = false
+			}
+			reply = "$-1\r\n"
+			if ok {
+				reply = "$" + strconv.Itoa(len(value)) + "\r\n" + string(value) + "\r\n"
+			}
+		case name == "SET":
+			r.values[args[1]] = []byte(args[2])
+			delete(r.expires, args[1])
+			if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+				ms, _ := strconv.Atoi(args[4])
+				r.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+			reply = "+OK\r\n"
+		case name == "DEL":
+			_, ok := r.values[args[1]]
+			delete(r.values, args[1])
+			reply = ":0\r\n"
+			if ok {
+				reply = ":1\r\n"
+			}
+		}
+		r.mu.Unlock()
+
+		if _, err = conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (r *redisStandIn) Commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.commands...)
+}
+
+func TestRedisStore(t *testing.T) {
+	standIn, addr := newRedisStandIn(t, "secret")
+	store := NewRedisStore(addr, WithRedisPassword("secret"), WithRedisDB(2), WithRedisKeyPrefix("test:"))
+	defer store.Close()
+
+	testCacheStore(t, store)
+
+	commands := standIn.Commands()
+	if want := []string{"AUTH secret", "SELECT 2", "GET test:missing"}; len(commands) < 3 || strings.Join(commands[:3], ",") != strings.Join(want, ",") {
+		t.Errorf("commands = %q, want to start with %q", commands, want)
+	}
+	if got := strings.Count(strings.Join(commands, ","), "AUTH"); got != 1 {
+		t.Errorf("AUTH sent %d times, want 1 as the connection is reused", got)
+	}
+	if !strings.Contains(strings.Join(commands, ","), "PX 60000") {
+		t.Errorf("commands = %q, want SET with PX of the ttl", commands)
+	}
+}
+
+func TestRedisStoreErrors(t *testing.T) {
+	_, addr := newRedisStandIn(t, "secret")
+	ctx := context.Background()
+
+	var rerr redisError
+	if _, err := NewRedisStore(addr).Get(ctx, "key"); !errors.As(err, &rerr) {
+		t.Errorf("Get() without password error = %v, want the error reply", err)
+	}
+	if _, err := NewRedisStore(addr, WithRedisPassword("wrong")).Get(ctx, "key"); !errors.As(err, &rerr) {
+		t.Errorf("Get() with wrong password error = %v, want the error reply", err)
+	}
+
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := l.Addr().String()
+	l.Close()
+	if err := NewRedisStore(closed).Set(ctx, "key", []byte("value"), 0); err == nil {
+		t.Errorf("Set() of unreachable server error = nil, want an error")
+	}
+}
+
+func TestResponseCacheRedisStore(t *testing.T) {
+	_, addr := newRedisStandIn(t, "")
+	streams := &cachedStreams{policy: CachePolicy{MaxAge: 60}}
+
+	// two replicas share the responses
+	for range 2 {
+		store := NewRedisStore(addr)
+		defer store.Close()
+		s := newCachedServer(t, streams, NewResponseCache(WithCacheStore(store)))
+
+		if code, title := getStreamTitle(s, "/token/stream/movie/tt0111161.json"); code != http.StatusOK || title != "1" {
+			t.Errorf("response = %d %q, want 200 \"1\"", code, title)
+		}
+	}
+
+	if got := streams.calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1 as the replicas share the cache", got)
+	}
+}
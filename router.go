@@ -1,14 +1,13 @@
 package stremigo
 
 import (
-	"encoding/json"
 	"net/http"
-	"strings"
 )
 
 var (
-	EnabledEndpoints = [6]string{
+	EnabledEndpoints = [7]string{
 		PathManifest,
+		PathAddonCatalog,
 		PathCatalog,
 		PathMeta,
 		PathStream,
@@ -26,86 +25,21 @@ func isEnabledEnpoint(endpoint string) bool {
 	return false
 }
 
-func setHeaders(w http.ResponseWriter) {
-
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-	w.Header().Set("Content-Type", "application/json")
-
-}
-
+// Router - serves ProviderInterface, see ContextRouter
 func Router(w http.ResponseWriter, r *http.Request, p ProviderInterface) {
+	ContextRouter(w, r, AdaptProvider(p))
+}
 
-	if r.Method == http.MethodOptions {
-		setHeaders(w)
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	t := ""
-
-	// Unsecured
-	if len(parts) == 1 {
-		switch parts[0] {
-		case "", "login":
-			http.Redirect(w, r, "/configure", http.StatusMovedPermanently)
-			return
-		case PathConfigure:
-			p.RenderConfigurePage(w, r, t)
-			return
-		default:
-			if !isEnabledEnpoint(parts[0]) {
-				http.Error(w, "Page not found", http.StatusNotFound)
-				return
-			}
-			break
-		}
-	}
-
-	// Secured
-
-	if p.IsSecured() {
-
-		if len(parts) < 2 {
-			http.Error(w, "Neplatná cesta. Očekává se /<token>/<resource>", http.StatusBadRequest)
-			return
-		}
-
-		t = parts[0]
-
-		// remove token from path
-		r.URL.Path = "/" + strings.Join(parts[1:], "/")
-	}
-
-	var data any
-
-	switch parts[1] {
-	case PathManifest:
-		data = p.GetManifest(w, r, t)
-		break
-	case PathCatalog:
-		data = p.GetCatalog(w, r, t)
-		break
-	case PathMeta:
-		data = p.GetMeta(w, r, t)
-		break
-	case PathStream:
-		data = p.GetStream(w, r, t)
-		break
-	case PathConfigure:
-		p.RenderConfigurePage(w, r, t)
-		return
-	default:
-		http.Error(w, "Page not found", http.StatusNotFound)
-		return
-	}
-
-	if data == nil {
-		return
+// result - converts typed provider result to any, so nil pointers are not hidden in a non-nil interface
+func result[T any](v *T, err error) (any, error) {
+	if v == nil {
+		return nil, err
 	}
+	return v, err
+}
 
-	setHeaders(w)
-	json.NewEncoder(w).Encode(data)
+// ContextRouter - serves manifest, resources and configure page of ContextProvider with the default options,
+// use NewServer to configure the behaviour
+func ContextRouter(w http.ResponseWriter, r *http.Request, p ContextProvider) {
+	NewServer(p).ServeHTTP(w, r)
 }
@@ -1,11 +1,151 @@
 package stremigo
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
+// mockProvider - ProviderInterface recording the arguments it was called with
+type mockProvider struct {
+	secured bool
+	token   string
+	args    any
+}
+
+func (m *mockProvider) GetManifest(w http.ResponseWriter, r *http.Request, token string) *AddonManifest {
+	m.token = token
+	return &AddonManifest{ID: "com.example.mock", Name: "Mock"}
+}
+
+func (m *mockProvider) GetCatalog(w http.ResponseWriter, r *http.Request, token string, args *CatalogArgs) *MetaPreviewList {
+	m.token, m.args = token, args
+	return &MetaPreviewList{Metas: []*MetaPreview{}}
+}
+
+func (m *mockProvider) GetMeta(w http.ResponseWriter, r *http.Request, token string, args *MetaArgs) *Meta {
+	m.token, m.args = token, args
+	return &Meta{ID: args.ID, Type: args.Type}
+}
+
+func (m *mockProvider) GetStream(w http.ResponseWriter, r *http.Request, token string, args *StreamArgs) *StreamList {
+	m.token, m.args = token, args
+	return &StreamList{Streams: []*Stream{}}
+}
+
+func (m *mockProvider) GetSubtitles(w http.ResponseWriter, r *http.Request, token string, args *SubtitlesArgs) *SubtitlesList {
+	m.token, m.args = token, args
+	return &SubtitlesList{
+		Subtitles:   []*Subtitles{{ID: "1", URL: "https://example.com/" + args.ID + ".srt", Lang: "eng"}},
+		CacheMaxAge: 3600,
+	}
+}
+
+func (m *mockProvider) RenderConfigurePage(w http.ResponseWriter, r *http.Request, token string) {
+	m.token = token
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *mockProvider) IsSecured() bool {
+	return m.secured
+}
+
+func TestRouterArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantArgs any
+	}{
+		{
+			name:     "catalog",
+			path:     "/token/catalog/movie/top.json",
+			wantCode: http.StatusOK,
+			wantArgs: &CatalogArgs{Type: TypeMovie, ID: "top", Extra: ExtraArgs{}},
+		},
+		{
+			name:     "catalog with extra",
+			path:     "/token/catalog/movie/top/genre=Action&skip=100.json",
+			wantCode: http.StatusOK,
+			wantArgs: &CatalogArgs{
+				Type:  TypeMovie,
+				ID:    "top",
+				Extra: ExtraArgs{CatalogExtraGenre: {"Action"}, CatalogExtraSkip: {"100"}},
+			},
+		},
+		{
+			name:     "meta",
+			path:     "/token/meta/series/tt0903747.json",
+			wantCode: http.StatusOK,
+			wantArgs: &MetaArgs{Type: TypeSeries, ID: "tt0903747", Extra: ExtraArgs{}},
+		},
+		{
+			name:     "stream with encoded id",
+			path:     "/token/stream/series/tt0903747%3A1%3A2.json",
+			wantCode: http.StatusOK,
+			wantArgs: &StreamArgs{Type: TypeSeries, ID: "tt0903747:1:2", Extra: ExtraArgs{}},
+		},
+		{
+			name:     "subtitles with video extras",
+			path:     "/token/subtitles/movie/tt0111161/filename=The%20Movie.mkv&videoHash=8e245d9679d31e12&videoSize=1073741824.json",
+			wantCode: http.StatusOK,
+			wantArgs: &SubtitlesArgs{
+				Type: TypeMovie,
+				ID:   "tt0111161",
+				Extra: ExtraArgs{
+					SubtitlesExtraFilename:  {"The Movie.mkv"},
+					SubtitlesExtraVideoHash: {"8e245d9679d31e12"},
+					SubtitlesExtraVideoSize: {"1073741824"},
+				},
+				VideoHash: "8e245d9679d31e12",
+				VideoSize: 1073741824,
+				Filename:  "The Movie.mkv",
+			},
+		},
+		{
+			name:     "subtitles with invalid video size",
+			path:     "/token/subtitles/movie/tt0111161/videoSize=big.json",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "missing json suffix",
+			path:     "/token/catalog/movie/top",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "missing id",
+			path:     "/token/meta/movie.json",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				p := &mockProvider{secured: true}
+				rr := httptest.NewRecorder()
+
+				Router(rr, httptest.NewRequest(http.MethodGet, tt.path, nil), p)
+
+				if rr.Code != tt.wantCode {
+					t.Fatalf("Router(%q) status = %d, want %d", tt.path, rr.Code, tt.wantCode)
+				}
+				if !reflect.DeepEqual(p.args, tt.wantArgs) {
+					t.Errorf("Router(%q) args = %+v, want %+v", tt.path, p.args, tt.wantArgs)
+				}
+				if tt.wantArgs != nil && p.token != "token" {
+					t.Errorf("Router(%q) token = %q, want %q", tt.path, p.token, "token")
+				}
+			},
+		)
+	}
+}
+
 func TestSetHeaders(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -33,10 +173,10 @@ func TestSetHeaders(t *testing.T) {
 		t.Run(
 			tt.name, func(t *testing.T) {
 				// Use a ResponseRecorder to capture the headers
-				rr := &httptest.ResponseRecorder{HeaderMap: http.Header{}}
+				rr := httptest.NewRecorder()
 
-				// Call the setHeaders function
-				setHeaders(rr)
+				// Serve manifest with the default CORS policy
+				ContextRouter(rr, httptest.NewRequest(http.MethodGet, "/"+PathManifest, nil), &mockContextProvider{})
 
 				// Check expected headers
 				for key, value := range tt.expected {
@@ -68,6 +208,11 @@ func TestIsEnabledEndpoint(t *testing.T) {
 			endpoint: PathManifest,
 			want:     true,
 		},
+		{
+			name:     "Valid endpoint - " + PathAddonCatalog,
+			endpoint: PathAddonCatalog,
+			want:     true,
+		},
 		{
 			name:     "Valid endpoint - " + PathCatalog,
 			endpoint: PathCatalog,
@@ -131,3 +276,353 @@ func TestIsEnabledEndpoint(t *testing.T) {
 		)
 	}
 }
+
+func TestRouterSubtitles(t *testing.T) {
+	p := &mockProvider{secured: true}
+	rr := httptest.NewRecorder()
+
+	Router(rr, httptest.NewRequest(http.MethodGet, "/token/subtitles/series/tt0903747:1:2/videoHash=abc.json", nil), p)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want %q", got, "application/json")
+	}
+
+	var got SubtitlesList
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	want := SubtitlesList{
+		Subtitles:   []*Subtitles{{ID: "1", URL: "https://example.com/tt0903747:1:2.srt", Lang: "eng"}},
+		CacheMaxAge: 3600,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("response = %+v, want %+v", got, want)
+	}
+
+	args, ok := p.args.(*SubtitlesArgs)
+	if !ok {
+		t.Fatalf("GetSubtitles args = %T, want *SubtitlesArgs", p.args)
+	}
+	if args.VideoHash != "abc" {
+		t.Errorf("VideoHash = %q, want %q", args.VideoHash, "abc")
+	}
+}
+
+// mockAddonCatalogProvider - mockProvider serving addon catalogs as well
+type mockAddonCatalogProvider struct {
+	mockProvider
+}
+
+func (m *mockAddonCatalogProvider) GetAddonCatalog(w http.ResponseWriter, r *http.Request, token string, args *AddonCatalogArgs) *AddonCatalogList {
+	m.token, m.args = token, args
+	return &AddonCatalogList{
+		Addons: []*AddonCatalog{
+			{
+				TransportName: TransportHttp,
+				TransportUrl:  "https://example.com/manifest.json",
+				Manifest:      &AddonManifest{ID: "com.example.other", Name: "Other"},
+			},
+		},
+	}
+}
+
+func TestRouterAddonCatalog(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   ProviderInterface
+		wantCode   int
+		wantAddons int
+	}{
+		{
+			name:       "provider with addon catalogs",
+			provider:   &mockAddonCatalogProvider{mockProvider{secured: true}},
+			wantCode:   http.StatusOK,
+			wantAddons: 1,
+		},
+		{
+			name:     "provider without addon catalogs",
+			provider: &mockProvider{secured: true},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+
+				Router(rr, httptest.NewRequest(http.MethodGet, "/token/addon_catalog/all/store.json", nil), tt.provider)
+
+				if rr.Code != tt.wantCode {
+					t.Fatalf("status = %d, want %d", rr.Code, tt.wantCode)
+				}
+				if tt.wantCode != http.StatusOK {
+					return
+				}
+
+				var got AddonCatalogList
+				if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+					t.Fatalf("decoding response: %v", err)
+				}
+				if len(got.Addons) != tt.wantAddons {
+					t.Errorf("len(Addons) = %d, want %d", len(got.Addons), tt.wantAddons)
+				}
+
+				want := &AddonCatalogArgs{Type: "all", ID: "store", Extra: ExtraArgs{}}
+				if args := tt.provider.(*mockAddonCatalogProvider).args; !reflect.DeepEqual(args, want) {
+					t.Errorf("args = %+v, want %+v", args, want)
+				}
+			},
+		)
+	}
+}
+
+func TestRouterLayouts(t *testing.T) {
+	tests := []struct {
+		name      string
+		secured   bool
+		path      string
+		wantCode  int
+		wantToken string
+		wantArgs  any
+	}{
+		{
+			name:     "unsecured - manifest",
+			path:     "/" + PathManifest,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "unsecured - catalog",
+			path:     "/catalog/movie/top.json",
+			wantCode: http.StatusOK,
+			wantArgs: &CatalogArgs{Type: TypeMovie, ID: "top", Extra: ExtraArgs{}},
+		},
+		{
+			name:     "unsecured - stream",
+			path:     "/stream/series/tt0903747:1:2.json",
+			wantCode: http.StatusOK,
+			wantArgs: &StreamArgs{Type: TypeSeries, ID: "tt0903747:1:2", Extra: ExtraArgs{}},
+		},
+		{
+			name:     "unsecured - configure",
+			path:     "/" + PathConfigure,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "unsecured - root redirects to configure",
+			path:     "/",
+			wantCode: http.StatusMovedPermanently,
+		},
+		{
+			name:     "unsecured - resource without arguments",
+			path:     "/catalog",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unsecured - token is not a resource",
+			path:     "/token/catalog/movie/top.json",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "unsecured - unknown page",
+			path:     "/unknown",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:      "secured - manifest",
+			secured:   true,
+			path:      "/token/" + PathManifest,
+			wantCode:  http.StatusOK,
+			wantToken: "token",
+		},
+		{
+			name:      "secured - catalog",
+			secured:   true,
+			path:      "/token/catalog/movie/top.json",
+			wantCode:  http.StatusOK,
+			wantToken: "token",
+			wantArgs:  &CatalogArgs{Type: TypeMovie, ID: "top", Extra: ExtraArgs{}},
+		},
+		{
+			name:      "secured - configure with token",
+			secured:   true,
+			path:      "/token/" + PathConfigure,
+			wantCode:  http.StatusOK,
+			wantToken: "token",
+		},
+		{
+			name:     "secured - configure without token",
+			secured:  true,
+			path:     "/" + PathConfigure,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "secured - manifest without token",
+			secured:  true,
+			path:     "/" + PathManifest,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "secured - root redirects to configure",
+			secured:  true,
+			path:     "/",
+			wantCode: http.StatusMovedPermanently,
+		},
+		{
+			name:     "secured - unknown resource",
+			secured:  true,
+			path:     "/token/unknown/movie/top.json",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				p := &mockProvider{secured: tt.secured}
+				rr := httptest.NewRecorder()
+
+				Router(rr, httptest.NewRequest(http.MethodGet, tt.path, nil), p)
+
+				if rr.Code != tt.wantCode {
+					t.Fatalf("Router(%q) status = %d, want %d", tt.path, rr.Code, tt.wantCode)
+				}
+				if p.token != tt.wantToken {
+					t.Errorf("Router(%q) token = %q, want %q", tt.path, p.token, tt.wantToken)
+				}
+				if !reflect.DeepEqual(p.args, tt.wantArgs) {
+					t.Errorf("Router(%q) args = %+v, want %+v", tt.path, p.args, tt.wantArgs)
+				}
+			},
+		)
+	}
+}
+
+// mockContextProvider - ContextProvider returning preset error from every resource
+type mockContextProvider struct {
+	err error
+}
+
+func (m *mockContextProvider) Manifest(ctx context.Context, token string) (*AddonManifest, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &AddonManifest{ID: "com.example.mock", Name: "Mock"}, nil
+}
+
+func (m *mockContextProvider) Catalog(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &MetaPreviewList{Metas: []*MetaPreview{}}, nil
+}
+
+func (m *mockContextProvider) Meta(ctx context.Context, token string, args *MetaArgs) (*Meta, error) {
+	return nil, m.err
+}
+
+func (m *mockContextProvider) Stream(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &StreamList{Streams: []*Stream{}}, nil
+}
+
+func (m *mockContextProvider) Subtitles(ctx context.Context, token string, args *SubtitlesArgs) (*SubtitlesList, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &SubtitlesList{Subtitles: []*Subtitles{}}, nil
+}
+
+func (m *mockContextProvider) RenderConfigurePage(w http.ResponseWriter, r *http.Request, token string) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *mockContextProvider) IsSecured() bool {
+	return false
+}
+
+func TestContextRouterErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		err      error
+		wantCode int
+	}{
+		{
+			name:     "success",
+			path:     "/stream/movie/tt0111161.json",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "nil result without error",
+			path:     "/meta/movie/tt0111161.json",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "not found",
+			path:     "/stream/movie/tt0111161.json",
+			err:      ErrNotFound,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "wrapped bad request",
+			path:     "/catalog/movie/top/skip=x.json",
+			err:      fmt.Errorf("invalid skip: %w", ErrBadRequest),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unauthorized",
+			path:     "/" + PathManifest,
+			err:      ErrUnauthorized,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "upstream unavailable",
+			path:     "/subtitles/movie/tt0111161.json",
+			err:      ErrUpstreamUnavailable,
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "deadline exceeded",
+			path:     "/stream/movie/tt0111161.json",
+			err:      context.DeadlineExceeded,
+			wantCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:     "unknown error",
+			path:     "/stream/movie/tt0111161.json",
+			err:      errors.New("boom"),
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "manifest with trailing segments",
+			path:     "/" + PathManifest + "/x",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "addon catalog is optional",
+			path:     "/addon_catalog/all/store.json",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+
+				ContextRouter(rr, httptest.NewRequest(http.MethodGet, tt.path, nil), &mockContextProvider{err: tt.err})
+
+				if rr.Code != tt.wantCode {
+					t.Errorf("ContextRouter(%q) status = %d, want %d", tt.path, rr.Code, tt.wantCode)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,378 @@
+package stremigo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// errMissingToken - secured addon requested without the token segment
+var errMissingToken = fmt.Errorf("%w: expected /<token>/<resource>", ErrBadRequest)
+
+// ErrorHandler - writes an error returned by ContextProvider or found while routing, see StatusCode
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// Option - configures Server created by NewServer
+type Option func(s *Server)
+
+// Server - http.Handler serving manifest, resources and configure page of ContextProvider
+type Server struct {
+	provider         ContextProvider
+	basePath         string
+	cors             *CORSPolicy
+	logger           *slog.Logger
+	cachePolicies    map[string]CachePolicy
+	validateManifest bool
+	filterIDs        bool
+	declared         declaredManifests
+	errorHandler     ErrorHandler
+	decodeToken      func(ctx context.Context, token string) (context.Context, error)
+	authenticator    Authenticator
+	responseCache    *ResponseCache
+	middleware       []func(http.Handler) http.Handler
+	handler          http.Handler
+
+	resourceMiddleware []ResourceMiddleware
+	resourceHandler    ResourceHandler
+}
+
+// NewServer - creates Server serving the provider; responses setting no cache policy get no Cache-Control
+// unless WithCachePolicy sets a default
+func NewServer(provider ContextProvider, opts ...Option) *Server {
+	cors := DefaultCORSPolicy
+
+	s := &Server{
+		provider:      provider,
+		cors:          &cors,
+		cachePolicies: map[string]CachePolicy{},
+		declared:      declaredManifests{ttl: DefaultIDFilterTTL, now: time.Now},
+		errorHandler:  DefaultErrorHandler,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	middleware := s.resourceMiddleware
+	if s.responseCache != nil {
+		middleware = slices.Concat(middleware, []ResourceMiddleware{s.responseCache.middleware(s.cachePolicies)})
+	}
+	middleware = slices.Concat(middleware, []ResourceMiddleware{bindValidators})
+	if s.filterIDs {
+		middleware = append([]ResourceMiddleware{s.filterUndeclared}, middleware...)
+	}
+	if s.authenticator != nil {
+		middleware = append([]ResourceMiddleware{authorize}, middleware...)
+	}
+	s.resourceHandler = chainResource(s.dispatch, middleware)
+
+	s.handler = http.HandlerFunc(s.serve)
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		s.handler = s.middleware[i](s.handler)
+	}
+
+	return s
+}
+
+// WithBasePath - serves the addon under the path prefix, e.g. "/addons/foo" when mounted as
+// mux.Handle("/addons/foo/", server); requests outside the prefix are answered with ErrNotFound
+func WithBasePath(prefix string) Option {
+	return func(s *Server) {
+		if prefix = strings.Trim(prefix, "/"); prefix != "" {
+			s.basePath = "/" + prefix
+		} else {
+			s.basePath = ""
+		}
+	}
+}
+
+// WithCORS - allows only the origin by DefaultCORSPolicy, "*" by default; empty origin disables CORS headers
+// completely, see WithCORSPolicy for full configuration
+func WithCORS(origin string) Option {
+	return func(s *Server) {
+		if origin == "" {
+			s.cors = nil
+			return
+		}
+
+		policy := DefaultCORSPolicy
+		policy.AllowedOrigins = []string{origin}
+		s.cors = &policy
+	}
+}
+
+// WithLogger - logs every request and provider failures
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithCachePolicy - sets default CachePolicy of the resource (PathManifest, PathCatalog, PathMeta, ...),
+// used when the response doesn't set its own
+func WithCachePolicy(resource string, policy CachePolicy) Option {
+	return func(s *Server) {
+		s.cachePolicies[resource] = policy
+	}
+}
+
+// WithManifestValidation - refuses to serve a manifest failing AddonManifest.Validate; the validation report
+// is returned with status 500 instead, so an invalid addon cannot be installed. Custom content
+// types have to be added to KnownTypes first.
+func WithManifestValidation(validate bool) Option {
+	return func(s *Server) {
+		s.validateManifest = validate
+	}
+}
+
+// WithErrorHandler - replaces DefaultErrorHandler, e.g. by LocalizedErrorHandler; CORS headers are set
+// before the handler is called
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(s *Server) {
+		s.errorHandler = handler
+	}
+}
+
+// WithMiddleware - wraps the server by HTTP middleware; the first middleware is the outermost one
+func WithMiddleware(middleware ...func(http.Handler) http.Handler) Option {
+	return func(s *Server) {
+		s.middleware = append(s.middleware, middleware...)
+	}
+}
+
+// statusRecorder - remembers the status code written to the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.logger == nil {
+		s.handler.ServeHTTP(w, r)
+		return
+	}
+
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	s.handler.ServeHTTP(rec, r)
+
+	s.logger.LogAttrs(
+		r.Context(), slog.LevelInfo, "stremigo request",
+		slog.String("method", r.Method),
+		slog.String("path", s.logPath(r)),
+		slog.Int("status", rec.status),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+// logPath - path of the request with the token segment replaced by "<token>", so the access log holds
+// no user config or credential
+func (s *Server) logPath(r *http.Request) string {
+	path := r.URL.Path
+	if !s.provider.IsSecured() {
+		return path
+	}
+
+	rest, ok := strings.CutPrefix(path, s.basePath+"/")
+	if !ok {
+		return path
+	}
+	// the token is the first non-empty segment, as in splitPath
+	if _, resource, ok := strings.Cut(strings.TrimLeft(rest, "/"), "/"); ok {
+		return s.basePath + "/<token>/" + resource
+	}
+	return path
+}
+
+// fail - writes the error unless the response has already been written or the client is gone
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errResponseWritten) {
+		return
+	}
+	if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+		return
+	}
+
+	if s.logger != nil && StatusCode(err) >= http.StatusInternalServerError {
+		s.logger.LogAttrs(r.Context(), slog.LevelError, "stremigo provider failed", slog.String("path", r.URL.Path), slog.Any("error", err))
+	}
+
+	s.errorHandler(w, r, err)
+}
+
+// splitPath - splits escaped path without the base path to escaped and unescaped segments
+func (s *Server) splitPath(r *http.Request) (raw, parts []string, err error) {
+	path := r.URL.EscapedPath()
+	if s.basePath != "" {
+		if path != s.basePath && !strings.HasPrefix(path, s.basePath+"/") {
+			return nil, nil, ErrNotFound
+		}
+		path = strings.TrimPrefix(path, s.basePath)
+	}
+
+	raw = strings.Split(strings.Trim(path, "/"), "/")
+	parts = make([]string, len(raw))
+	for i, segment := range raw {
+		if parts[i], err = url.PathUnescape(segment); err != nil {
+			return nil, nil, ErrInvalidPath
+		}
+	}
+	return raw, parts, nil
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	p := s.provider
+
+	if s.cors != nil && isPreflight(r) {
+		s.cors.preflight(w, r)
+		return
+	}
+
+	// CORS headers are set on every response, so Stremio web client can read errors as well
+	if s.cors != nil {
+		s.cors.setHeaders(w, r)
+	}
+
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	raw, parts, err := s.splitPath(r)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	t := ""
+
+	// Unsecured
+	if len(parts) == 1 {
+		switch parts[0] {
+		case "", "login":
+			http.Redirect(w, r, s.basePath+"/"+PathConfigure, http.StatusMovedPermanently)
+			return
+		case PathConfigure:
+			p.RenderConfigurePage(w, r, t)
+			return
+		default:
+			if !isEnabledEnpoint(parts[0]) {
+				s.fail(w, r, ErrNotFound)
+				return
+			}
+			break
+		}
+	}
+
+	// Resource path - the whole path when unsecured
+	resource, rawResource := parts, raw
+
+	// the request is modified below, don't change the one owned by the caller
+	r = r.WithContext(r.Context())
+	u := *r.URL
+	r.URL = &u
+
+	// Secured - the first segment is the token
+	if p.IsSecured() {
+
+		if len(parts) < 2 {
+			s.fail(w, r, errMissingToken)
+			return
+		}
+
+		t = parts[0]
+		resource, rawResource = parts[1:], raw[1:]
+	}
+
+	// remove base path and token from path
+	r.URL.Path = "/" + strings.Join(resource, "/")
+	r.URL.RawPath = "/" + strings.Join(rawResource, "/")
+
+	switch resource[0] {
+	case PathManifest, PathAddonCatalog, PathCatalog, PathMeta, PathStream, PathSubtitles:
+	case PathConfigure:
+		p.RenderConfigurePage(w, r, t)
+		return
+	default:
+		s.fail(w, r, ErrNotFound)
+		return
+	}
+
+	// the token is checked before the path, so unauthorized clients learn nothing about the resources
+	if s.decodeToken != nil && p.IsSecured() {
+		ctx, err := s.decodeToken(r.Context(), t)
+		if err != nil {
+			s.fail(w, r, err)
+			return
+		}
+		r = r.WithContext(ctx)
+	}
+	if s.authenticator != nil {
+		ctx, err := s.authenticate(r.Context(), t)
+		if err != nil {
+			s.fail(w, r, err)
+			return
+		}
+		r = r.WithContext(ctx)
+	}
+
+	req, err := newResourceRequest(resource[0], t, rawResource[1:])
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+
+	ctx := withValidators(r.Context())
+	data, err := s.resourceHandler(withExchange(ctx, w, r), req)
+
+	if err == nil && data == nil {
+		err = ErrNotFound
+	}
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+
+	// the response is encoded before it is written, so its ETag can be computed and compared first
+	var body bytes.Buffer
+	if err = json.NewEncoder(&body).Encode(data); err != nil {
+		s.fail(w, r, err)
+		return
+	}
+
+	v := validatorsFromContext(ctx)
+	etag := v.etagOf(body.Bytes())
+	_, lastModified := v.get()
+
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	setCacheControl(w, s.cachePolicies, req, data)
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body.Bytes())
+}
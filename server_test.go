@@ -0,0 +1,166 @@
+package stremigo
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerBasePath(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/addons/foo/", NewServer(AdaptProvider(&mockProvider{secured: true}), WithBasePath("/addons/foo/")))
+
+	tests := []struct {
+		name         string
+		path         string
+		wantCode     int
+		wantLocation string
+	}{
+		{name: "manifest", path: "/addons/foo/token/manifest.json", wantCode: http.StatusOK},
+		{name: "catalog", path: "/addons/foo/token/catalog/movie/top.json", wantCode: http.StatusOK},
+		{name: "configure", path: "/addons/foo/configure", wantCode: http.StatusOK},
+		{
+			name:         "root redirects under base path",
+			path:         "/addons/foo/",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/addons/foo/configure",
+		},
+		{name: "missing token", path: "/addons/foo/manifest.json", wantCode: http.StatusBadRequest},
+		{name: "outside of mux", path: "/addons/bar/token/manifest.json", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+
+				mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+				if rr.Code != tt.wantCode {
+					t.Fatalf("status = %d, want %d", rr.Code, tt.wantCode)
+				}
+				if got := rr.Header().Get("Location"); got != tt.wantLocation {
+					t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+				}
+			},
+		)
+	}
+}
+
+func TestServerBasePathOutsidePrefix(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	NewServer(&mockContextProvider{}, WithBasePath("addons/foo")).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/addons/foobar/manifest.json", nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestServerDoesNotModifyRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/token/stream/movie/tt0111161.json", nil)
+
+	NewServer(AdaptProvider(&mockProvider{secured: true})).ServeHTTP(httptest.NewRecorder(), r)
+
+	if got, want := r.URL.Path, "/token/stream/movie/tt0111161.json"; got != want {
+		t.Errorf("URL.Path = %q, want %q", got, want)
+	}
+}
+
+func TestServerOptions(t *testing.T) {
+	var log bytes.Buffer
+	var order []string
+
+	middleware := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					order = append(order, name)
+					next.ServeHTTP(w, r)
+				},
+			)
+		}
+	}
+
+	s := NewServer(
+		&mockContextProvider{},
+		WithLogger(slog.New(slog.NewTextHandler(&log, nil))),
+		WithCachePolicy(PathCatalog, CachePolicy{MaxAge: 60}),
+		WithMiddleware(middleware("outer"), middleware("inner")),
+		WithErrorHandler(
+			func(w http.ResponseWriter, r *http.Request, err error) {
+				w.WriteHeader(StatusCode(err))
+				w.Write([]byte("custom"))
+			},
+		),
+	)
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/catalog/movie/top.json", nil))
+
+	if got := rr.Header().Get("Cache-Control"); got != "max-age=60, public" {
+		t.Errorf("Cache-Control = %q, want %q", got, "max-age=60, public")
+	}
+	if got := strings.Join(order, ","); got != "outer,inner" {
+		t.Errorf("middleware order = %q, want %q", got, "outer,inner")
+	}
+	if !strings.Contains(log.String(), "status=200") {
+		t.Errorf("log = %q, want request with status=200", log.String())
+	}
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/unknown/movie/top.json", nil))
+
+	if rr.Code != http.StatusNotFound || rr.Body.String() != "custom" {
+		t.Errorf("response = %d %q, want %d %q", rr.Code, rr.Body.String(), http.StatusNotFound, "custom")
+	}
+}
+
+func TestServerLogPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		secured  bool
+		basePath string
+		path     string
+		want     string
+	}{
+		{name: "unsecured", path: "/catalog/movie/top.json", want: "/catalog/movie/top.json"},
+		{name: "token", secured: true, path: "/secret/manifest.json", want: "/<token>/manifest.json"},
+		{name: "token after slashes", secured: true, path: "//secret/manifest.json", want: "/<token>/manifest.json"},
+		{name: "base path", secured: true, basePath: "/addon", path: "/addon/secret/manifest.json", want: "/addon/<token>/manifest.json"},
+		{name: "configure page", secured: true, path: "/configure", want: "/configure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var log bytes.Buffer
+
+				NewServer(
+					AdaptProvider(&mockProvider{secured: tt.secured}),
+					WithBasePath(tt.basePath),
+					WithLogger(slog.New(slog.NewTextHandler(&log, nil))),
+				).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+				if !strings.Contains(log.String(), "path="+tt.want+" ") || strings.Contains(log.String(), "secret") {
+					t.Errorf("log = %q, want path=%s without the token", log.String(), tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestServerManifestValidation(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	NewServer(&manifestProvider{manifest: &AddonManifest{ID: "invalid"}}, WithManifestValidation(true)).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+PathManifest, nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusInternalServerError)
+	}
+}
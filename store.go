@@ -0,0 +1,116 @@
+package stremigo
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss - CacheStore has no value of the key, or it has expired
+var ErrCacheMiss = errors.New("stremigo: cache miss")
+
+// CacheStore - storage of ResponseCache, see MemoryStore, FileStore and RedisStore; FileStore lets the cache
+// of a single process outlive restarts, RedisStore shares it by all replicas of the addon
+type CacheStore interface {
+	// Get - returns the value of the key, ErrCacheMiss when there is none
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set - stores the value of the key for ttl, zero ttl means no expiry
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete - removes the value of the key, missing key is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore - CacheStore keeping the values in memory, the least recently used values are evicted
+// when the store is full
+type MemoryStore struct {
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// memoryEntry - value of MemoryStore
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryStore - creates MemoryStore of at most maxEntries values, zero means no limit
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Len - returns the number of stored values including expired ones not evicted yet
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	entry := el.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && !s.now().Before(entry.expires) {
+		s.remove(el)
+		return nil, ErrCacheMiss
+	}
+
+	s.lru.MoveToFront(el)
+	return entry.value, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := &memoryEntry{key: key, value: slices.Clone(value)}
+	if ttl > 0 {
+		entry.expires = s.now().Add(ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		el.Value = entry
+		s.lru.MoveToFront(el)
+		return nil
+	}
+
+	s.entries[key] = s.lru.PushFront(entry)
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+	}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+	return nil
+}
+
+// remove - removes the element from the list and the map, the caller holds the lock
+func (s *MemoryStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,75 @@
+package stremigo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	_ CacheStore = (*MemoryStore)(nil)
+	_ CacheStore = (*FileStore)(nil)
+	_ CacheStore = (*RedisStore)(nil)
+)
+
+// testCacheStore - checks the behaviour shared by all CacheStore implementations
+func testCacheStore(t *testing.T, store CacheStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() of missing key error = %v, want ErrCacheMiss", err)
+	}
+
+	key := "token\x00stream\x00movie\x00tt0111161\x00"
+	for _, value := range []string{`{"streams":[]}`, `{"streams":[{"url":"https://example.com"}]}`} {
+		if err := store.Set(ctx, key, []byte(value), time.Minute); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+		if got, err := store.Get(ctx, key); err != nil || string(got) != value {
+			t.Errorf("Get() = %q, %v, want %q", got, err, value)
+		}
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() of deleted key error = %v, want ErrCacheMiss", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() of missing key error = %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testCacheStore(t, NewMemoryStore(10))
+}
+
+func TestMemoryStoreExpiryAndEviction(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{now: time.Now()}
+	store := NewMemoryStore(2)
+	store.now = clock.Now
+
+	store.Set(ctx, "a", []byte("a"), time.Minute)
+	store.Set(ctx, "b", []byte("b"), 0)
+	store.Get(ctx, "a")
+	store.Set(ctx, "c", []byte("c"), 0)
+
+	if _, err := store.Get(ctx, "b"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() of least recently used key error = %v, want ErrCacheMiss", err)
+	}
+
+	clock.Add(time.Minute)
+	if _, err := store.Get(ctx, "a"); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Get() of expired key error = %v, want ErrCacheMiss", err)
+	}
+	if got, err := store.Get(ctx, "c"); err != nil || string(got) != "c" {
+		t.Errorf("Get() of key without expiry = %q, %v, want %q", got, err, "c")
+	}
+	if got := store.Len(); got != 1 {
+		t.Errorf("Len() = %d, want 1", got)
+	}
+}
@@ -1,19 +1,54 @@
 package stremigo
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 type ProviderInterface interface {
 	GetManifest(w http.ResponseWriter, r *http.Request, token string) *AddonManifest
 
-	GetCatalog(w http.ResponseWriter, r *http.Request, token string) *MetaPreviewList
+	GetCatalog(w http.ResponseWriter, r *http.Request, token string, args *CatalogArgs) *MetaPreviewList
 
-	GetMeta(w http.ResponseWriter, r *http.Request, token string) *Meta
+	GetMeta(w http.ResponseWriter, r *http.Request, token string, args *MetaArgs) *Meta
 
-	GetStream(w http.ResponseWriter, r *http.Request, token string) *StreamList
+	GetStream(w http.ResponseWriter, r *http.Request, token string, args *StreamArgs) *StreamList
 
-	GetSubtitles(w http.ResponseWriter, r *http.Request, token string) *SubtitlesList
+	GetSubtitles(w http.ResponseWriter, r *http.Request, token string, args *SubtitlesArgs) *SubtitlesList
 
 	RenderConfigurePage(w http.ResponseWriter, r *http.Request, token string)
 
 	IsSecured() bool
 }
+
+// AddonCatalogProviderInterface - optional capability of ProviderInterface serving addon collections
+// from /addon_catalog/{type}/{id}.json; declare the catalogs in AddonManifest.AddonCatalogs
+type AddonCatalogProviderInterface interface {
+	GetAddonCatalog(w http.ResponseWriter, r *http.Request, token string, args *AddonCatalogArgs) *AddonCatalogList
+}
+
+// ContextProvider - second generation of ProviderInterface; methods receive the request context and typed
+// arguments and return (result, error) instead of writing errors to the response themselves. Router maps
+// ErrBadRequest, ErrUnauthorized, ErrNotFound and ErrUpstreamUnavailable to HTTP status codes, a nil result
+// without an error is answered as ErrNotFound. Use AdaptProvider to serve ProviderInterface implementations.
+type ContextProvider interface {
+	Manifest(ctx context.Context, token string) (*AddonManifest, error)
+
+	Catalog(ctx context.Context, token string, args *CatalogArgs) (*MetaPreviewList, error)
+
+	Meta(ctx context.Context, token string, args *MetaArgs) (*Meta, error)
+
+	Stream(ctx context.Context, token string, args *StreamArgs) (*StreamList, error)
+
+	Subtitles(ctx context.Context, token string, args *SubtitlesArgs) (*SubtitlesList, error)
+
+	RenderConfigurePage(w http.ResponseWriter, r *http.Request, token string)
+
+	IsSecured() bool
+}
+
+// AddonCatalogContextProvider - optional capability of ContextProvider serving addon collections
+// from /addon_catalog/{type}/{id}.json; declare the catalogs in AddonManifest.AddonCatalogs
+type AddonCatalogContextProvider interface {
+	AddonCatalog(ctx context.Context, token string, args *AddonCatalogArgs) (*AddonCatalogList, error)
+}
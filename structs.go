@@ -1,5 +1,10 @@
 package stremigo
 
+import (
+	"bytes"
+	"encoding/json"
+)
+
 // AddonCatalog - define addons catalog
 // TransportName - required - string, only TransportHttp is currently officially supported
 // TransportUrl - required - string, the URL of the addon's manifest.json file
@@ -17,43 +22,139 @@ type AddonCatalogList struct {
 }
 
 // AddonManifestBehaviorHints - define configuration properties
+// Adult - optional - boolean, if the addon includes adult content
+// P2P - optional - boolean, if the addon includes P2P content, such as BitTorrent, which may reveal the user's IP to other streaming parties
+// Configurable - optional - boolean, if the addon supports settings, adds a button next to "Install" in Stremio that will point to the /configure path
+// ConfigurationRequired - optional - boolean, if the addon requires settings to be set before it can be installed, "Install" button is replaced by "Configure"
+// NewEpisodeNotifications - optional - boolean, if the addon's catalogs support the lastVideosIds extra, used for new episode notifications
 type AddonManifestBehaviorHints struct {
-	Configurable bool `json:"configurable"`
+	Adult                   bool `json:"adult,omitempty"`
+	P2P                     bool `json:"p2p,omitempty"`
+	Configurable            bool `json:"configurable"`
+	ConfigurationRequired   bool `json:"configurationRequired,omitempty"`
+	NewEpisodeNotifications bool `json:"newEpisodeNotifications,omitempty"`
+}
+
+// ConfigField - user setting of the addon, see AddonManifest.Config
+// Key - required - string, identifier of the setting
+// Type - required - string, type of the setting; "text", "number", "password", "checkbox" or "select"
+// Default - optional - string, default value; for "checkbox" use "checked" to check it by default
+// Title - optional - string, human readable title of the setting
+// Options - optional - array of strings, possible values of a "select" setting
+// Required - optional - boolean, if the setting must be set
+type ConfigField struct {
+	Key      string   `json:"key"`
+	Type     string   `json:"type"`
+	Default  string   `json:"default,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Options  []string `json:"options,omitempty"`
+	Required bool     `json:"required,omitempty"`
 }
 
 // AddonManifest - define add properties
 // ID - required - string, identifier, dot-separated, e.g. "com.stremio.filmon"
 // Name - required - string, human readable name
 // Description - required - string, human readable description
+// Logo - optional - string, URL to png of the addon's logo, monochrome, 256x256
+// Background - optional - string, URL to png/jpg of the addon's background, at least 1024x786
+// ContactEmail - optional - string, contact email for addon issues, used for the Report button in the app
 // Version - required - string, semantic version of the addon
 // Resources - required - array of Resource
 // Types - required - array of strings, types of content supported by the addon. [ TypeMovie, TypeSeries, TypeChannel, TypeTv ]
 // Catalogs - required - array of Catalog objects, lists of movies/series
+// AddonCatalogs - optional - array of Catalog objects, lists of other addons served by ResourceAddonCatalog
 // Prefixes - optional - array of strings, prefix for the addon's content, e.g. ["com.stremio.filmon.movies", "com.stremio.filmon.series"]
+// Config - optional - array of ConfigField objects, user settings of the addon
 // BehaviorHints - optional - @see AddonManifestBehaviorHints
 type AddonManifest struct {
 	ID            string                      `json:"id"`
 	Version       string                      `json:"version"`
 	Name          string                      `json:"name"`
 	Logo          string                      `json:"logo"`
+	Background    string                      `json:"background,omitempty"`
 	Description   string                      `json:"description"`
+	ContactEmail  string                      `json:"contactEmail,omitempty"`
 	Resources     []*Resource                 `json:"resources"`
 	Types         []string                    `json:"types"`
 	Catalogs      []*Catalog                  `json:"catalogs"`
+	AddonCatalogs []*Catalog                  `json:"addonCatalogs,omitempty"`
 	Prefixes      []string                    `json:"idPrefixes,omitempty"`
+	Config        []*ConfigField              `json:"config,omitempty"`
 	BehaviorHints *AddonManifestBehaviorHints `json:"behaviorHints,omitempty"`
 }
 
+// addonManifestObject - AddonManifest without custom marshalling
+type addonManifestObject AddonManifest
+
+// MarshalJSON - encodes resources limited only by prefixes with the manifest Types, as the object form
+// of Resource requires types
+func (m AddonManifest) MarshalJSON() ([]byte, error) {
+	resources := make([]*Resource, len(m.Resources))
+	for i, r := range m.Resources {
+		if r != nil && len(r.Type) == 0 && len(r.Prefixes) > 0 {
+			r = &Resource{Name: r.Name, Type: m.Types, Prefixes: r.Prefixes}
+		}
+		resources[i] = r
+	}
+	if m.Resources == nil {
+		resources = nil
+	}
+
+	obj := addonManifestObject(m)
+	obj.Resources = resources
+	return json.Marshal(obj)
+}
+
 // Resource - define add properties
 // Name - required - string, only supported. [ ResourceCatalog, ResourceMeta, ResourceStream, ResourceSubtitles, ResourceAddonCatalog ]
 // Type - optional - array of strings, only supported types. [ TypeMovie, TypeSeries, TypeChannel, TypeTv ]
 // Prefixes - optional - array of strings, prefix for the addon's content, e.g. ["com.stremio.filmon.movies", "com.stremio.filmon.series"]
+//
+// Resource without Type and Prefixes is encoded in its short string form, e.g. "catalog", and both forms are
+// accepted when decoding. Short form resource uses AddonManifest.Types and AddonManifest.Prefixes.
 type Resource struct {
 	Name     string   `json:"name"`
 	Type     []string `json:"types,omitempty"`
 	Prefixes []string `json:"idPrefixes,omitempty"`
 }
 
+// resourceObject - Resource without custom (un)marshalling
+type resourceObject Resource
+
+// MarshalJSON - encodes the short form when neither Type nor Prefixes are set; the object form always has
+// "types", which Stremio requires, AddonManifest fills in its own Types when Type is empty
+func (r Resource) MarshalJSON() ([]byte, error) {
+	if len(r.Type) == 0 && len(r.Prefixes) == 0 {
+		return json.Marshal(r.Name)
+	}
+
+	types := r.Type
+	if types == nil {
+		types = []string{}
+	}
+	return json.Marshal(
+		struct {
+			Name     string   `json:"name"`
+			Type     []string `json:"types"`
+			Prefixes []string `json:"idPrefixes,omitempty"`
+		}{Name: r.Name, Type: types, Prefixes: r.Prefixes},
+	)
+}
+
+func (r *Resource) UnmarshalJSON(data []byte) error {
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '"' {
+		*r = Resource{}
+		return json.Unmarshal(data, &r.Name)
+	}
+
+	var obj resourceObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*r = Resource(obj)
+	return nil
+}
+
 // Catalog - Movies/Series lists
 // ID - required - string, the id of the catalog, can be any unique string describing the catalog (unique per addon, as an addon can have many catalogs), for example: if the catalog name is "Favourite Youtube Videos", the id can be "fav_youtube_videos"
 // Type - required - string, this is the content type of the catalog. [ TypeMovie, TypeSeries, TypeChannel, TypeTv ]
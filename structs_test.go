@@ -0,0 +1,133 @@
+package stremigo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestResourceMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource *Resource
+		want     string
+	}{
+		{
+			name:     "short form",
+			resource: &Resource{Name: ResourceCatalog},
+			want:     `"catalog"`,
+		},
+		{
+			name:     "object with types",
+			resource: &Resource{Name: ResourceStream, Type: []string{TypeMovie}},
+			want:     `{"name":"stream","types":["movie"]}`,
+		},
+		{
+			name:     "object with prefixes",
+			resource: &Resource{Name: ResourceMeta, Prefixes: []string{PrefixImdb}},
+			want:     `{"name":"meta","types":[],"idPrefixes":["tt"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := json.Marshal(tt.resource)
+				if err != nil {
+					t.Fatalf("Marshal() error = %v", err)
+				}
+				if string(got) != tt.want {
+					t.Errorf("Marshal() = %s, want %s", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestAddonManifestMarshalJSON(t *testing.T) {
+	m := AddonManifest{
+		Types:     []string{TypeMovie, TypeSeries},
+		Resources: []*Resource{{Name: ResourceCatalog}, {Name: ResourceMeta, Prefixes: []string{PrefixImdb}}},
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var got struct {
+		Resources []json.RawMessage `json:"resources"`
+	}
+	json.Unmarshal(data, &got)
+	if want := `{"name":"meta","types":["movie","series"],"idPrefixes":["tt"]}`; len(got.Resources) != 2 || string(got.Resources[1]) != want {
+		t.Errorf("resources = %s, want %s with the manifest types", data, want)
+	}
+	if m.Resources[1].Type != nil {
+		t.Errorf("Marshal() modified the resource types to %v", m.Resources[1].Type)
+	}
+}
+
+func TestAddonManifestUnmarshalJSON(t *testing.T) {
+	data := `{
+		"id": "com.example.wild",
+		"version": "1.0.0",
+		"name": "Wild",
+		"description": "Manifest as found in the wild",
+		"background": "https://example.com/bg.jpg",
+		"contactEmail": "addon@example.com",
+		"resources": ["catalog", {"name": "stream", "types": ["movie"], "idPrefixes": ["tt"]}],
+		"types": ["movie"],
+		"catalogs": [],
+		"addonCatalogs": [{"id": "store", "type": "movie", "name": "Store"}],
+		"config": [{"key": "apiKey", "type": "password", "title": "API key", "required": true}],
+		"behaviorHints": {"adult": true, "p2p": true, "configurable": true, "configurationRequired": true, "newEpisodeNotifications": true}
+	}`
+
+	var got AddonManifest
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := AddonManifest{
+		ID:           "com.example.wild",
+		Version:      "1.0.0",
+		Name:         "Wild",
+		Description:  "Manifest as found in the wild",
+		Background:   "https://example.com/bg.jpg",
+		ContactEmail: "addon@example.com",
+		Resources: []*Resource{
+			{Name: ResourceCatalog},
+			{Name: ResourceStream, Type: []string{TypeMovie}, Prefixes: []string{PrefixImdb}},
+		},
+		Types:         []string{TypeMovie},
+		Catalogs:      []*Catalog{},
+		AddonCatalogs: []*Catalog{{ID: "store", Type: TypeMovie, Name: "Store"}},
+		Config:        []*ConfigField{{Key: "apiKey", Type: "password", Title: "API key", Required: true}},
+		BehaviorHints: &AddonManifestBehaviorHints{
+			Adult:                   true,
+			P2P:                     true,
+			Configurable:            true,
+			ConfigurationRequired:   true,
+			NewEpisodeNotifications: true,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+	}
+
+	// round trip keeps the short and the object form
+	encoded, err := json.Marshal(got.Resources)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `["catalog",{"name":"stream","types":["movie"],"idPrefixes":["tt"]}]`; string(encoded) != want {
+		t.Errorf("Marshal(Resources) = %s, want %s", encoded, want)
+	}
+}
+
+func TestResourceUnmarshalJSONInvalid(t *testing.T) {
+	var r Resource
+	if err := json.Unmarshal([]byte(`42`), &r); err == nil {
+		t.Error("Unmarshal(42) error = nil, want error")
+	}
+}
@@ -0,0 +1,178 @@
+package stremigo
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidToken - token can't be decoded by TokenCodec, it was tampered with or created with another key
+var ErrInvalidToken = fmt.Errorf("%w: invalid token", ErrUnauthorized)
+
+// TokenCodec - encodes user configuration to the token of secured addon paths and decodes it back;
+// Decode fails with ErrInvalidToken
+type TokenCodec interface {
+	Encode(config any) (string, error)
+	Decode(token string, v any) error
+}
+
+// JSONTokenCodec - base64url encoded JSON; readable and modifiable by anyone, use it for non-sensitive settings only
+type JSONTokenCodec struct{}
+
+func (JSONTokenCodec) Encode(config any) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func (JSONTokenCodec) Decode(token string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return unmarshalToken(data, v)
+}
+
+// unmarshalToken - decodes JSON payload of the token
+func unmarshalToken(data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// HMACTokenCodec - base64url encoded JSON signed by HMAC-SHA256, in the form payload.signature;
+// readable by anyone, but can't be modified without the key
+type HMACTokenCodec struct {
+	key []byte
+}
+
+// MinHMACKeySize - the shortest key accepted by NewHMACTokenCodec, the size of SHA-256 output
+const MinHMACKeySize = sha256.Size
+
+// NewHMACTokenCodec - creates HMACTokenCodec signing by the key of at least MinHMACKeySize random bytes
+func NewHMACTokenCodec(key []byte) (*HMACTokenCodec, error) {
+	if len(key) < MinHMACKeySize {
+		return nil, fmt.Errorf("stremigo: token codec: HMAC key must have at least %d bytes, got %d", MinHMACKeySize, len(key))
+	}
+	return &HMACTokenCodec{key: slices.Clone(key)}, nil
+}
+
+func (c *HMACTokenCodec) Encode(config any) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(c.sign(payload)), nil
+}
+
+func (c *HMACTokenCodec) Decode(token string, v any) error {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("%w: missing signature", ErrInvalidToken)
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(payload)) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return unmarshalToken(data, v)
+}
+
+func (c *HMACTokenCodec) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// AESTokenCodec - JSON encrypted by AES-GCM with a random nonce, base64url encoded; neither readable
+// nor modifiable without the key, use it for credentials like API keys
+type AESTokenCodec struct {
+	aead cipher.AEAD
+}
+
+// NewAESTokenCodec - creates AESTokenCodec; the key must have 16, 24 or 32 bytes to select AES-128, AES-192 or AES-256
+func NewAESTokenCodec(key []byte) (*AESTokenCodec, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("stremigo: token codec: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("stremigo: token codec: %w", err)
+	}
+
+	return &AESTokenCodec{aead: aead}, nil
+}
+
+func (c *AESTokenCodec) Encode(config any) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(data)+c.aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(c.aead.Seal(nonce, nonce, data, nil)), nil
+}
+
+func (c *AESTokenCodec) Decode(token string, v any) error {
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return fmt.Errorf("%w: too short", ErrInvalidToken)
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	data, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return fmt.Errorf("%w: decryption failed", ErrInvalidToken)
+	}
+	return unmarshalToken(data, v)
+}
+
+// configKey - context key of the config decoded from the token
+type configKey struct{}
+
+// WithTokenCodec - decodes the token of secured addon to T by the codec before the provider is called;
+// the config is available by ConfigFromContext[T], undecodable tokens are answered with ErrInvalidToken
+func WithTokenCodec[T any](codec TokenCodec) Option {
+	return func(s *Server) {
+		s.decodeToken = func(ctx context.Context, token string) (context.Context, error) {
+			var config T
+			if err := codec.Decode(token, &config); err != nil {
+				return ctx, err
+			}
+			return context.WithValue(ctx, configKey{}, config), nil
+		}
+	}
+}
+
+// ConfigFromContext - returns config decoded from the token by the codec of WithTokenCodec
+func ConfigFromContext[T any](ctx context.Context) (T, bool) {
+	config, ok := ctx.Value(configKey{}).(T)
+	return config, ok
+}
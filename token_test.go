@@ -0,0 +1,194 @@
+package stremigo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type tokenConfig struct {
+	APIKey string `json:"apiKey"`
+	Limit  int    `json:"limit"`
+}
+
+func newTestCodecs(t *testing.T) map[string]TokenCodec {
+	t.Helper()
+
+	aesCodec, err := NewAESTokenCodec([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewAESTokenCodec() error = %v", err)
+	}
+
+	return map[string]TokenCodec{
+		"json": JSONTokenCodec{},
+		"hmac": newTestHMACCodec(t, testHMACKey),
+		"aes":  aesCodec,
+	}
+}
+
+const testHMACKey = "0123456789abcdef0123456789abcdef"
+
+func newTestHMACCodec(t *testing.T, key string) *HMACTokenCodec {
+	t.Helper()
+
+	codec, err := NewHMACTokenCodec([]byte(key))
+	if err != nil {
+		t.Fatalf("NewHMACTokenCodec() error = %v", err)
+	}
+	return codec
+}
+
+// tamperToken - replaces both last characters of the token by other ones
+func tamperToken(token string) string {
+	tampered := []byte(token)
+	for i := len(tampered) - 2; i < len(tampered); i++ {
+		if tampered[i] == 'x' {
+			tampered[i] = 'y'
+		} else {
+			tampered[i] = 'x'
+		}
+	}
+	return string(tampered)
+}
+
+func TestTokenCodecs(t *testing.T) {
+	want := tokenConfig{APIKey: "a/b+c?", Limit: 20}
+
+	for name, codec := range newTestCodecs(t) {
+		t.Run(
+			name, func(t *testing.T) {
+				token, err := codec.Encode(want)
+				if err != nil {
+					t.Fatalf("Encode() error = %v", err)
+				}
+				if url.PathEscape(token) != token {
+					t.Errorf("Encode() = %q, want a path-safe token", token)
+				}
+
+				var got tokenConfig
+				if err = codec.Decode(token, &got); err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if got != want {
+					t.Errorf("Decode() = %+v, want %+v", got, want)
+				}
+
+				for _, bad := range []string{"", "not a token", tamperToken(token)} {
+					if err = codec.Decode(bad, &got); !errors.Is(err, ErrInvalidToken) || !errors.Is(err, ErrUnauthorized) {
+						t.Errorf("Decode(%q) error = %v, want ErrInvalidToken", bad, err)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestHMACTokenCodecTampered(t *testing.T) {
+	if _, err := NewHMACTokenCodec([]byte("secret")); err == nil {
+		t.Error("NewHMACTokenCodec() with short key succeeded, want error")
+	}
+
+	codec := newTestHMACCodec(t, testHMACKey)
+
+	token, _ := codec.Encode(tokenConfig{APIKey: "user"})
+	_, signature, _ := strings.Cut(token, ".")
+	forged, _ := JSONTokenCodec{}.Encode(tokenConfig{APIKey: "admin"})
+
+	var got tokenConfig
+	if err := codec.Decode(forged+"."+signature, &got); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Decode() of forged payload error = %v, want ErrInvalidToken", err)
+	}
+	if err := newTestHMACCodec(t, "fedcba9876543210fedcba9876543210").Decode(token, &got); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Decode() with another key error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestAESTokenCodec(t *testing.T) {
+	if _, err := NewAESTokenCodec([]byte("short")); err == nil {
+		t.Error("NewAESTokenCodec() with invalid key size succeeded, want error")
+	}
+
+	codec, _ := NewAESTokenCodec([]byte("0123456789abcdef"))
+	first, _ := codec.Encode(tokenConfig{APIKey: "secret"})
+	second, _ := codec.Encode(tokenConfig{APIKey: "secret"})
+
+	if first == second {
+		t.Error("Encode() returned the same token twice, want random nonce")
+	}
+	if strings.Contains(first, "secret") {
+		t.Errorf("Encode() = %q, want encrypted config", first)
+	}
+}
+
+func TestServerTokenCodec(t *testing.T) {
+	codec := newTestHMACCodec(t, testHMACKey)
+	called := 0
+
+	addon, err := NewAddonBuilder(testManifest).
+		DefineStreamHandler(
+			[]string{TypeMovie}, nil,
+			func(ctx context.Context, token string, args *StreamArgs) (*StreamList, error) {
+				called++
+				config, ok := ConfigFromContext[tokenConfig](ctx)
+				if !ok {
+					return nil, errors.New("missing config")
+				}
+				return &StreamList{Streams: []*Stream{{Title: config.APIKey}}}, nil
+			},
+		).
+		Secured(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	server := NewServer(addon, WithTokenCodec[tokenConfig](codec))
+	token, _ := codec.Encode(tokenConfig{APIKey: "secret"})
+	forged, _ := JSONTokenCodec{}.Encode(tokenConfig{APIKey: "secret"})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantCode   int
+		wantCalled int
+	}{
+		{name: "valid token", path: "/" + token + "/stream/movie/tt0111161.json", wantCode: http.StatusOK, wantCalled: 1},
+		{name: "forged token", path: "/" + forged + "/stream/movie/tt0111161.json", wantCode: http.StatusUnauthorized},
+		{name: "forged token of invalid path", path: "/" + forged + "/stream/movie.json", wantCode: http.StatusUnauthorized},
+		{name: "configure page ignores token", path: "/" + forged + "/configure", wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				called = 0
+				rr := httptest.NewRecorder()
+
+				server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+				if rr.Code != tt.wantCode {
+					t.Fatalf("status = %d, want %d: %s", rr.Code, tt.wantCode, rr.Body)
+				}
+				if called != tt.wantCalled {
+					t.Errorf("handler called %d times, want %d", called, tt.wantCalled)
+				}
+				if rr.Code != http.StatusOK {
+					return
+				}
+
+				var got StreamList
+				if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+					t.Fatalf("decode error = %v", err)
+				}
+				if len(got.Streams) != 1 || got.Streams[0].Title != "secret" {
+					t.Errorf("streams = %+v, want title from the decoded config", got.Streams)
+				}
+			},
+		)
+	}
+}
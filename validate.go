@@ -0,0 +1,317 @@
+package stremigo
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// manifestIDPattern - dot-separated identifier, e.g. "com.stremio.filmon"
+	manifestIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)+$`)
+
+	// semverPattern - semantic version 2.0.0, see https://semver.org
+	semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(-(0|[1-9]\d*|\d*[A-Za-z-][0-9A-Za-z-]*)(\.(0|[1-9]\d*|\d*[A-Za-z-][0-9A-Za-z-]*))*)?` +
+		`(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+	// KnownTypes - content types accepted by Validate; append custom types, e.g. "anime", before serving them
+	KnownTypes = []string{TypeMovie, TypeSeries, TypeChannel, TypeTv}
+
+	// KnownResources - resources accepted by Validate
+	KnownResources = []string{ResourceAddonCatalog, ResourceCatalog, ResourceMeta, ResourceStream, ResourceSubtitles}
+
+	// KnownConfigTypes - config field types accepted by Validate
+	KnownConfigTypes = []string{ConfigTypeText, ConfigTypeNumber, ConfigTypePassword, ConfigTypeCheckbox, ConfigTypeSelect}
+)
+
+// ValidationError - single problem found by Validate
+// Field - path of the invalid field in JSON notation, e.g. "catalogs[0].extra[1].optionsLimit"
+// Message - human readable description of the problem
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors - all problems found by Validate or AddonManifest.ParseConfigForm; use errors.As to inspect single problems
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "stremigo: invalid manifest: " + strings.Join(messages, "; ")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// add - appends a problem of the field
+func (e *ValidationErrors) add(field, format string, args ...any) {
+	*e = append(*e, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err - returns nil when there are no problems, so the result can be compared to nil
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// field - joins field path of a nested object
+func field(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// Validate - checks required fields, identifiers, versions, types, resources and catalogs;
+// returns ValidationErrors listing all problems or nil
+func (m *AddonManifest) Validate() error {
+	var errs ValidationErrors
+
+	switch {
+	case m.ID == "":
+		errs.add("id", "is required")
+	case !manifestIDPattern.MatchString(m.ID):
+		errs.add("id", "%q is not a dot-separated identifier", m.ID)
+	}
+
+	switch {
+	case m.Version == "":
+		errs.add("version", "is required")
+	case !semverPattern.MatchString(m.Version):
+		errs.add("version", "%q is not a semantic version", m.Version)
+	}
+
+	if m.Name == "" {
+		errs.add("name", "is required")
+	}
+	if m.Description == "" {
+		errs.add("description", "is required")
+	}
+
+	if len(m.Resources) == 0 {
+		errs.add("resources", "at least one resource is required")
+	}
+	for i, r := range m.Resources {
+		errs = append(errs, r.validate(fmt.Sprintf("resources[%d]", i))...)
+	}
+
+	if len(m.Types) == 0 {
+		errs.add("types", "at least one type is required")
+	}
+	for i, t := range m.Types {
+		if !slices.Contains(KnownTypes, t) {
+			errs.add(fmt.Sprintf("types[%d]", i), "unknown type %q", t)
+		}
+	}
+
+	errs = append(errs, m.validateCatalogs("catalogs", m.Catalogs)...)
+	errs = append(errs, m.validateCatalogs("addonCatalogs", m.AddonCatalogs)...)
+
+	seen := map[string]bool{}
+	for i, f := range m.Config {
+		prefix := fmt.Sprintf("config[%d]", i)
+		errs = append(errs, f.validate(prefix)...)
+
+		if f != nil && f.Key != "" && seen[f.Key] {
+			errs.add(field(prefix, "key"), "duplicate key %q", f.Key)
+		}
+		if f != nil {
+			seen[f.Key] = true
+		}
+	}
+
+	return errs.err()
+}
+
+// validateCatalogs - validates catalogs, their IDs uniqueness and presence of their types in AddonManifest.Types
+func (m *AddonManifest) validateCatalogs(name string, catalogs []*Catalog) ValidationErrors {
+	var errs ValidationErrors
+	seen := map[string]bool{}
+
+	for i, c := range catalogs {
+		prefix := fmt.Sprintf("%s[%d]", name, i)
+		errs = append(errs, c.validate(prefix)...)
+
+		// the same id may be used by catalogs of several types, e.g. "top" of movies and series
+		if c != nil && c.ID != "" && seen[c.Type+"/"+c.ID] {
+			errs.add(field(prefix, "id"), "duplicate catalog %s/%s", c.Type, c.ID)
+		}
+		if c != nil {
+			seen[c.Type+"/"+c.ID] = true
+		}
+
+		if c != nil && c.Type != "" && !slices.Contains(m.Types, c.Type) {
+			errs.add(field(prefix, "type"), "type %q is missing in types", c.Type)
+		}
+	}
+
+	return errs
+}
+
+// Validate - checks resource name and types; returns ValidationErrors listing all problems or nil
+func (r *Resource) Validate() error {
+	return r.validate("").err()
+}
+
+func (r *Resource) validate(prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	switch {
+	case r == nil:
+		errs.add(prefix, "is required")
+		return errs
+	case r.Name == "":
+		errs.add(field(prefix, "name"), "is required")
+	case !slices.Contains(KnownResources, r.Name):
+		errs.add(field(prefix, "name"), "unknown resource %q", r.Name)
+	}
+
+	for i, t := range r.Type {
+		if !slices.Contains(KnownTypes, t) {
+			errs.add(field(prefix, fmt.Sprintf("types[%d]", i)), "unknown type %q", t)
+		}
+	}
+
+	return errs
+}
+
+// Validate - checks required fields, type and extra properties; returns ValidationErrors listing all problems or nil
+func (c *Catalog) Validate() error {
+	return c.validate("").err()
+}
+
+func (c *Catalog) validate(prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if c == nil {
+		errs.add(prefix, "is required")
+		return errs
+	}
+
+	if c.ID == "" {
+		errs.add(field(prefix, "id"), "is required")
+	}
+
+	switch {
+	case c.Type == "":
+		errs.add(field(prefix, "type"), "is required")
+	case !slices.Contains(KnownTypes, c.Type):
+		errs.add(field(prefix, "type"), "unknown type %q", c.Type)
+	}
+
+	if c.Name == "" {
+		errs.add(field(prefix, "name"), "is required")
+	}
+
+	seen := map[string]bool{}
+	for i, e := range c.Extra {
+		extraPrefix := field(prefix, fmt.Sprintf("extra[%d]", i))
+		errs = append(errs, e.validate(extraPrefix)...)
+
+		if e != nil && e.Name != "" && seen[e.Name] {
+			errs.add(field(extraPrefix, "name"), "duplicate extra %q", e.Name)
+		}
+		if e != nil {
+			seen[e.Name] = true
+		}
+	}
+
+	return errs
+}
+
+// Validate - checks name and options limit; returns ValidationErrors listing all problems or nil
+func (e *CatalogExtra) Validate() error {
+	return e.validate("").err()
+}
+
+func (e *CatalogExtra) validate(prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if e == nil {
+		errs.add(prefix, "is required")
+		return errs
+	}
+
+	if e.Name == "" {
+		errs.add(field(prefix, "name"), "is required")
+	}
+
+	switch {
+	case e.OptionsLimit < 0:
+		errs.add(field(prefix, "optionsLimit"), "must not be negative")
+	case e.OptionsLimit > len(e.Options):
+		errs.add(field(prefix, "optionsLimit"), "%d is larger than the number of options (%d)", e.OptionsLimit, len(e.Options))
+	}
+
+	return errs
+}
+
+// Validate - checks key, type, options and default value; returns ValidationErrors listing all problems or nil
+func (f *ConfigField) Validate() error {
+	return f.validate("").err()
+}
+
+func (f *ConfigField) validate(prefix string) ValidationErrors {
+	var errs ValidationErrors
+
+	if f == nil {
+		errs.add(prefix, "is required")
+		return errs
+	}
+
+	if f.Key == "" {
+		errs.add(field(prefix, "key"), "is required")
+	}
+
+	switch {
+	case f.Type == "":
+		errs.add(field(prefix, "type"), "is required")
+	case !slices.Contains(KnownConfigTypes, f.Type):
+		errs.add(field(prefix, "type"), "unknown type %q", f.Type)
+	case f.Type == ConfigTypeSelect && len(f.Options) == 0:
+		errs.add(field(prefix, "options"), "at least one option is required")
+	case f.Type != ConfigTypeSelect && len(f.Options) > 0:
+		errs.add(field(prefix, "options"), "only select may have options")
+	}
+
+	if f.Default == "" {
+		return errs
+	}
+
+	switch f.Type {
+	case ConfigTypeSelect:
+		if len(f.Options) > 0 && !slices.Contains(f.Options, f.Default) {
+			errs.add(field(prefix, "default"), "%q is not one of the options", f.Default)
+		}
+	case ConfigTypeNumber:
+		if _, err := strconv.ParseFloat(f.Default, 64); err != nil {
+			errs.add(field(prefix, "default"), "%q is not a number", f.Default)
+		}
+	case ConfigTypeCheckbox:
+		if f.Default != ConfigCheckboxChecked {
+			errs.add(field(prefix, "default"), "must be empty or %q", ConfigCheckboxChecked)
+		}
+	}
+
+	return errs
+}
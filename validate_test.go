@@ -0,0 +1,240 @@
+package stremigo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func validManifest() *AddonManifest {
+	return &AddonManifest{
+		ID:          "com.example.valid",
+		Version:     "1.2.3-beta.1+build.5",
+		Name:        "Valid",
+		Description: "Valid addon",
+		Resources:   []*Resource{{Name: ResourceCatalog}, {Name: ResourceStream, Type: []string{TypeMovie}}},
+		Types:       []string{TypeMovie, TypeSeries},
+		Catalogs: []*Catalog{
+			{
+				ID:   "top",
+				Type: TypeMovie,
+				Name: "Top",
+				Extra: []*CatalogExtra{
+					{Name: CatalogExtraGenre, Options: []string{"Action", "Drama"}, OptionsLimit: 2},
+					{Name: CatalogExtraSkip},
+				},
+			},
+		},
+	}
+}
+
+// validationFields - returns fields of all ValidationError found in err
+func validationFields(err error) []string {
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+
+	fields := make([]string, len(verrs))
+	for i, e := range verrs {
+		fields[i] = e.Field
+	}
+	return fields
+}
+
+func TestAddonManifestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		modify     func(m *AddonManifest)
+		wantFields []string
+	}{
+		{
+			name:   "valid",
+			modify: func(m *AddonManifest) {},
+		},
+		{
+			name: "missing required fields",
+			modify: func(m *AddonManifest) {
+				*m = AddonManifest{}
+			},
+			wantFields: []string{"id", "version", "name", "description", "resources", "types"},
+		},
+		{
+			name: "id is not dot-separated",
+			modify: func(m *AddonManifest) {
+				m.ID = "example"
+			},
+			wantFields: []string{"id"},
+		},
+		{
+			name: "version is not semver",
+			modify: func(m *AddonManifest) {
+				m.Version = "1.0"
+			},
+			wantFields: []string{"version"},
+		},
+		{
+			name: "unknown resource",
+			modify: func(m *AddonManifest) {
+				m.Resources = append(m.Resources, &Resource{Name: "trailers"})
+			},
+			wantFields: []string{"resources[2].name"},
+		},
+		{
+			name: "unknown type",
+			modify: func(m *AddonManifest) {
+				m.Types = append(m.Types, "anime")
+				m.Resources = append(m.Resources, &Resource{Name: ResourceMeta, Type: []string{"anime"}})
+				m.Catalogs = append(m.Catalogs, &Catalog{ID: "top", Type: "anime", Name: "Top anime"})
+			},
+			wantFields: []string{"resources[2].types[0]", "types[2]", "catalogs[1].type"},
+		},
+		{
+			name: "nil catalogs",
+			modify: func(m *AddonManifest) {
+				m.Catalogs = []*Catalog{nil}
+				m.AddonCatalogs = []*Catalog{nil}
+			},
+			wantFields: []string{"catalogs[0]", "addonCatalogs[0]"},
+		},
+		{
+			name: "catalog id of several types",
+			modify: func(m *AddonManifest) {
+				m.Catalogs = append(m.Catalogs, &Catalog{ID: "top", Type: TypeSeries, Name: "Top series"})
+			},
+		},
+		{
+			name: "duplicate catalog",
+			modify: func(m *AddonManifest) {
+				m.Catalogs = append(m.Catalogs, &Catalog{ID: "top", Type: TypeMovie, Name: "Top again"})
+			},
+			wantFields: []string{"catalogs[1].id"},
+		},
+		{
+			name: "catalog type missing from types",
+			modify: func(m *AddonManifest) {
+				m.Catalogs[0].Type = TypeChannel
+			},
+			wantFields: []string{"catalogs[0].type"},
+		},
+		{
+			name: "options limit larger than options",
+			modify: func(m *AddonManifest) {
+				m.Catalogs[0].Extra[0].OptionsLimit = 3
+			},
+			wantFields: []string{"catalogs[0].extra[0].optionsLimit"},
+		},
+		{
+			name: "invalid addon catalog",
+			modify: func(m *AddonManifest) {
+				m.AddonCatalogs = []*Catalog{{ID: "store", Type: TypeMovie}}
+			},
+			wantFields: []string{"addonCatalogs[0].name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				m := validManifest()
+				tt.modify(m)
+
+				err := m.Validate()
+				if tt.wantFields == nil {
+					if err != nil {
+						t.Fatalf("Validate() error = %v, want nil", err)
+					}
+					return
+				}
+
+				if got := validationFields(err); !reflect.DeepEqual(got, tt.wantFields) {
+					t.Errorf("Validate() fields = %v, want %v (error: %v)", got, tt.wantFields, err)
+				}
+			},
+		)
+	}
+}
+
+func TestCatalogExtraValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		extra   *CatalogExtra
+		wantErr bool
+	}{
+		{name: "valid", extra: &CatalogExtra{Name: CatalogExtraSearched, IsRequired: true}},
+		{name: "missing name", extra: &CatalogExtra{}, wantErr: true},
+		{name: "negative limit", extra: &CatalogExtra{Name: CatalogExtraGenre, OptionsLimit: -1}, wantErr: true},
+		{name: "limit without options", extra: &CatalogExtra{Name: CatalogExtraGenre, OptionsLimit: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if err := tt.extra.Validate(); (err != nil) != tt.wantErr {
+					t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			},
+		)
+	}
+}
+
+// manifestProvider - mockContextProvider serving a fixed manifest
+type manifestProvider struct {
+	mockContextProvider
+	manifest *AddonManifest
+}
+
+func (p *manifestProvider) Manifest(ctx context.Context, token string) (*AddonManifest, error) {
+	return p.manifest, nil
+}
+
+func TestAddonManifestValidateCustomType(t *testing.T) {
+	known := KnownTypes
+	t.Cleanup(func() { KnownTypes = known })
+	KnownTypes = append(slices.Clone(known), "anime")
+
+	m := validManifest()
+	m.Types = append(m.Types, "anime")
+	m.Resources = append(m.Resources, &Resource{Name: ResourceMeta, Type: []string{"anime"}})
+	m.Catalogs = append(m.Catalogs, &Catalog{ID: "top", Type: "anime", Name: "Top anime"})
+
+	if err := m.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
+func TestServerValidateManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate bool
+		manifest *AddonManifest
+		wantCode int
+	}{
+		{name: "valid", validate: true, manifest: validManifest(), wantCode: http.StatusOK},
+		{name: "invalid", validate: true, manifest: &AddonManifest{ID: "invalid"}, wantCode: http.StatusInternalServerError},
+		{name: "invalid without validation", manifest: &AddonManifest{ID: "invalid"}, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+
+				NewServer(&manifestProvider{manifest: tt.manifest}, WithManifestValidation(tt.validate)).
+					ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+PathManifest, nil))
+
+				if rr.Code != tt.wantCode {
+					t.Fatalf("status = %d, want %d", rr.Code, tt.wantCode)
+				}
+				if rr.Code != http.StatusOK && !strings.Contains(rr.Body.String(), "id:") {
+					t.Errorf("body = %q, want validation report", rr.Body.String())
+				}
+			},
+		)
+	}
+}